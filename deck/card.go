@@ -0,0 +1,88 @@
+// Package deck holds flashcards and the operations on a deck of them:
+// loading and saving deck files, locking, adding and removing cards,
+// filtering and statistics. It never prints; callers report the errors and
+// load reports it returns.
+package deck
+
+import (
+	"strings"
+	"time"
+)
+
+type Flashcard struct {
+	ID             int               `json:"id"`
+	Question       string            `json:"question"`
+	Answer         string            `json:"answer"`
+	CorrectAnswers []string          `json:"correct_answers"`
+	Options        []string          `json:"options,omitempty"`
+	Essay          bool              `json:"essay,omitempty"`
+	Category       string            `json:"category"`
+	Tags           []string          `json:"tags,omitempty"`
+	Note           string            `json:"note,omitempty"`
+	Difficulty     string            `json:"difficulty"`
+	CreatedAt      time.Time         `json:"created_at"`
+	LastReviewed   *time.Time        `json:"last_reviewed,omitempty"`
+	LastCorrect    *bool             `json:"last_correct,omitempty"`
+	TimesReviewed  int               `json:"times_reviewed"`
+	TimesCorrect   int               `json:"times_correct"`
+	TotalTimeMs    int64             `json:"total_time_ms"`
+	TimesAnswered  int               `json:"times_answered,omitempty"`
+	Scheduler      string            `json:"scheduler,omitempty"`
+	EaseFactor     float64           `json:"ease_factor,omitempty"`
+	Interval       int               `json:"interval,omitempty"`
+	Repetitions    int               `json:"repetitions,omitempty"`
+	Box            int               `json:"box,omitempty"`
+	Stability      float64           `json:"stability,omitempty"`
+	FSRSDifficulty float64           `json:"fsrs_difficulty,omitempty"`
+	NextReview     *time.Time        `json:"next_review,omitempty"`
+	Flagged        bool              `json:"flagged,omitempty"`
+	FlagReason     string            `json:"flag_reason,omitempty"`
+	PrimaryAnswer  string            `json:"primary_answer,omitempty"`
+	OptionNotes    map[string]string `json:"option_notes,omitempty"`
+	TimesHinted    int               `json:"times_hinted,omitempty"`
+	MultiSelect    bool              `json:"multi_select,omitempty"`
+	Attachment     string            `json:"attachment,omitempty"`
+	DeletedAt      *time.Time        `json:"deleted_at,omitempty"`
+
+	// sourceFile is the deck file the card was loaded from when several
+	// decks are open at once; it is not stored.
+	sourceFile string
+
+	// fileID is the card's ID in its deck file when another open deck
+	// already uses it and the card got a different ID in memory; 0
+	// otherwise. Save writes the card back under fileID.
+	fileID int
+}
+
+const (
+	DifficultyEasy   = "easy"
+	DifficultyMedium = "medium"
+	DifficultyHard   = "hard"
+)
+
+var Difficulties = []string{DifficultyEasy, DifficultyMedium, DifficultyHard}
+
+func IsDifficulty(value string) bool {
+	for _, difficulty := range Difficulties {
+		if value == difficulty {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeQuestion is the form questions are compared in when looking for
+// duplicates: trimmed, lowercase and with single spaces.
+func NormalizeQuestion(question string) string {
+	return strings.ToLower(strings.Join(strings.Fields(question), " "))
+}
+
+// ContainsFold reports whether list contains value, ignoring case.
+func ContainsFold(list []string, value string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
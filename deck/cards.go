@@ -0,0 +1,164 @@
+package deck
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// NextID reserves and returns the next free card ID.
+func (d *Deck) NextID() int {
+	d.maxID++
+	return d.maxID
+}
+
+// FindByQuestion returns the first card whose question matches question
+// after NormalizeQuestion.
+func (d *Deck) FindByQuestion(question string) (Flashcard, bool) {
+	needle := NormalizeQuestion(question)
+	for _, card := range d.Flashcards {
+		if NormalizeQuestion(card.Question) == needle {
+			return card, true
+		}
+	}
+	return Flashcard{}, false
+}
+
+// NewCard builds a card with the next free ID and the usual defaults for
+// category and correct answers: a multiple-choice card without correct
+// answers accepts its first option, any other card its answer. It does not
+// add the card to the deck.
+func (d *Deck) NewCard(question, answer, category string, options, correctAnswers []string) Flashcard {
+	if category == "" {
+		category = "General"
+	}
+	if len(options) > 0 && len(correctAnswers) == 0 {
+		correctAnswers = []string{options[0]}
+	} else if len(options) == 0 {
+		correctAnswers = []string{answer}
+	}
+
+	return Flashcard{
+		ID:             d.NextID(),
+		Question:       question,
+		Answer:         answer,
+		CorrectAnswers: correctAnswers,
+		Options:        options,
+		Category:       category,
+		Difficulty:     DifficultyMedium,
+		CreatedAt:      time.Now(),
+		EaseFactor:     2.5,
+	}
+}
+
+// DuplicateError is returned by Add when the deck already has a card
+// asking the same question.
+type DuplicateError struct {
+	Existing Flashcard
+}
+
+func (e *DuplicateError) Error() string {
+	return fmt.Sprintf("card %d already asks '%s'", e.Existing.ID, e.Existing.Question)
+}
+
+// Add appends card to the deck without saving it. Unless allowDuplicate or
+// AllowDuplicates is set, a card whose question is already in the deck is
+// refused with a *DuplicateError.
+func (d *Deck) Add(card Flashcard, allowDuplicate bool) error {
+	if existing, found := d.FindByQuestion(card.Question); found && !allowDuplicate && !d.AllowDuplicates {
+		return &DuplicateError{Existing: existing}
+	}
+	d.Flashcards = append(d.Flashcards, card)
+	if d.idIndex != nil {
+		d.idIndex[card.ID] = len(d.Flashcards) - 1
+	}
+	return nil
+}
+
+// ErrCardNotFound is returned when no card has the requested ID.
+var ErrCardNotFound = errors.New("card not found")
+
+// Remove moves the card with the given ID to the trash without saving and
+// returns it.
+func (d *Deck) Remove(cardID int) (Flashcard, error) {
+	index, found := d.CardIndex(cardID)
+	if !found {
+		return Flashcard{}, fmt.Errorf("card %d: %w", cardID, ErrCardNotFound)
+	}
+	deleted := d.Flashcards[index]
+	now := time.Now()
+	deleted.DeletedAt = &now
+	d.Trash = append(d.Trash, deleted)
+	d.Flashcards = append(d.Flashcards[:index], d.Flashcards[index+1:]...)
+	return deleted, nil
+}
+
+// CardIndex returns the position of the card with the given ID in
+// Flashcards. Lookups go through idIndex; when the entry is missing or no
+// longer points at the card (after a load, add, delete or renumbering) the
+// index is rebuilt once, so only misses cost a scan.
+func (d *Deck) CardIndex(id int) (int, bool) {
+	if i, ok := d.idIndex[id]; ok && i < len(d.Flashcards) && d.Flashcards[i].ID == id {
+		return i, true
+	}
+	d.Reindex()
+	if i, ok := d.idIndex[id]; ok {
+		return i, true
+	}
+	return -1, false
+}
+
+// Reindex rebuilds idIndex from Flashcards. With duplicate IDs the first
+// card wins, as with a linear scan.
+func (d *Deck) Reindex() {
+	d.idIndex = make(map[int]int, len(d.Flashcards))
+	for i := len(d.Flashcards) - 1; i >= 0; i-- {
+		d.idIndex[d.Flashcards[i].ID] = i
+	}
+}
+
+// MergeResult counts what Merge did with the cards of the other deck.
+type MergeResult struct {
+	Added      int // appended to the deck
+	Renumbered int // appended under a different ID than in the other file
+	Deduped    int // matched a card with the same question
+}
+
+// Merge adds the cards of another deck without saving. Cards get fresh IDs
+// where their own is taken. A card whose normalized question already exists
+// is deduplicated: the copy with more reviews wins, keeping the current ID.
+func (d *Deck) Merge(other *Deck) MergeResult {
+	result := MergeResult{}
+	byQuestion := make(map[string]int, len(d.Flashcards))
+	usedIDs := make(map[int]bool, len(d.Flashcards))
+	for i, card := range d.Flashcards {
+		byQuestion[NormalizeQuestion(card.Question)] = i
+		usedIDs[card.ID] = true
+	}
+
+	for _, card := range other.Flashcards {
+		key := NormalizeQuestion(card.Question)
+		if index, exists := byQuestion[key]; exists {
+			if card.TimesReviewed > d.Flashcards[index].TimesReviewed {
+				card.ID = d.Flashcards[index].ID
+				card.sourceFile = d.Flashcards[index].sourceFile
+				card.fileID = d.Flashcards[index].fileID
+				d.Flashcards[index] = card
+			}
+			result.Deduped++
+			continue
+		}
+
+		if usedIDs[card.ID] {
+			card.ID = d.NextID()
+			result.Renumbered++
+		} else if card.ID > d.maxID {
+			d.maxID = card.ID
+		}
+		usedIDs[card.ID] = true
+		d.Flashcards = append(d.Flashcards, card)
+		byQuestion[key] = len(d.Flashcards) - 1
+		result.Added++
+	}
+	return result
+}
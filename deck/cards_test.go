@@ -0,0 +1,76 @@
+package deck
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"testing"
+)
+
+// linearIndex is CardIndex without the index: the first card with the ID.
+func linearIndex(cards []Flashcard, id int) (int, bool) {
+	for i, card := range cards {
+		if card.ID == id {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+// checkIndex compares CardIndex with a linear scan for every ID up to a few
+// past the highest one.
+func checkIndex(t *testing.T, d *Deck, step string) {
+	t.Helper()
+	for id := 0; id <= d.maxID+2; id++ {
+		wantIndex, wantFound := linearIndex(d.Flashcards, id)
+		gotIndex, gotFound := d.CardIndex(id)
+		if gotIndex != wantIndex || gotFound != wantFound {
+			t.Errorf("%s: CardIndex(%d) = %d, %v; want %d, %v", step, id, gotIndex, gotFound, wantIndex, wantFound)
+		}
+	}
+}
+
+func TestCardIndexMatchesLinearScan(t *testing.T) {
+	d := &Deck{}
+	add := func(n int) {
+		for i := 0; i < n; i++ {
+			card := d.NewCard("", "answer", "", nil, nil)
+			card.Question = fmt.Sprintf("Question %d", card.ID)
+			if err := d.Add(card, false); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	remove := func(ids ...int) {
+		for _, id := range ids {
+			if _, err := d.Remove(id); err != nil {
+				t.Fatalf("Remove(%d): %v", id, err)
+			}
+		}
+	}
+
+	checkIndex(t, d, "empty deck")
+	add(10)
+	checkIndex(t, d, "after adding 10 cards")
+	remove(1, 5, 10)
+	checkIndex(t, d, "after removing the first, a middle and the last card")
+	add(3)
+	checkIndex(t, d, "after adding 3 more cards")
+	remove(2, 3, 4, 12)
+	checkIndex(t, d, "after removing a run of cards")
+
+	// Changes made to Flashcards directly are picked up as well.
+	sort.Slice(d.Flashcards, func(i, j int) bool { return d.Flashcards[i].ID > d.Flashcards[j].ID })
+	checkIndex(t, d, "after reordering the cards")
+	d.Flashcards = append(d.Flashcards, Flashcard{ID: d.NextID(), Question: "appended"})
+	checkIndex(t, d, "after appending a card directly")
+	d.Flashcards = d.Flashcards[:3]
+	checkIndex(t, d, "after truncating the cards")
+
+	if len(d.Trash) != 7 {
+		t.Errorf("trash holds %d cards, want the 7 removed", len(d.Trash))
+	}
+	if _, err := d.Remove(1); !errors.Is(err, ErrCardNotFound) {
+		t.Errorf("removing a removed card: %v, want ErrCardNotFound", err)
+	}
+}
@@ -0,0 +1,499 @@
+package deck
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Deck is the set of cards of one deck file, or of several deck files open
+// at once.
+type Deck struct {
+	FilePath        string
+	FilePaths       []string // all decks when several are open; FilePath is the first
+	Flashcards      []Flashcard
+	Trash           []Flashcard
+	DryRun          bool
+	AllowDuplicates bool
+
+	// ReadOnly is set when a deck was written by a newer version of the
+	// app, or by callers that only read it without holding its lock. Save
+	// refuses to overwrite it and Load keeps its repairs in memory.
+	ReadOnly bool
+
+	maxID       int
+	lockedPaths []string
+	idIndex     map[int]int
+	schedulers  map[string]string
+}
+
+// New returns a deck for the file(s) in filePath, a comma-separated list of
+// paths, without loading them.
+func New(filePath string) *Deck {
+	paths := SplitPaths(filePath)
+	d := &Deck{FilePath: paths[0], Flashcards: []Flashcard{}}
+	if len(paths) > 1 {
+		d.FilePaths = paths
+	}
+	return d
+}
+
+// SplitPaths splits a comma-separated list of deck paths, dropping empty
+// entries.
+func SplitPaths(value string) []string {
+	paths := []string{}
+	for _, path := range strings.Split(value, ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			paths = append(paths, path)
+		}
+	}
+	if len(paths) == 0 {
+		return []string{value}
+	}
+	return paths
+}
+
+// Paths returns the paths of all open decks.
+func (d *Deck) Paths() []string {
+	if len(d.FilePaths) > 0 {
+		return d.FilePaths
+	}
+	return []string{d.FilePath}
+}
+
+// Name names the open deck(s) in headers.
+func (d *Deck) Name() string {
+	return strings.Join(d.Paths(), ", ")
+}
+
+// SchemaVersion is the deck file format this package writes. Version 1 was
+// a bare JSON array of cards; from version 2 on the cards are wrapped in a
+// deckFile, and version 3 added the trash.
+const SchemaVersion = 3
+
+type deckFile struct {
+	Version   int         `json:"version"`
+	Scheduler string      `json:"scheduler,omitempty"`
+	Cards     []Flashcard `json:"cards"`
+	Trash     []Flashcard `json:"trash,omitempty"`
+}
+
+// LoadReport describes what Load found in each deck file.
+type LoadReport struct {
+	Files []FileReport
+
+	// Renumbered counts the cards given a new ID in memory because an
+	// earlier deck already used theirs, when several decks are open.
+	Renumbered int
+}
+
+// FileReport describes the loading of one deck file.
+type FileReport struct {
+	Path    string
+	Cards   int
+	Missing bool // the file doesn't exist yet
+	Empty   bool
+	ReadErr error // the file could not be read; the deck starts empty
+
+	// DecodeErr is set when the file isn't a valid deck. Recovered tells
+	// whether its cards were then read one at a time; Total is the number
+	// of cards in the file and Skipped those that could not be read.
+	DecodeErr error
+	Recovered bool
+	Total     int
+	Skipped   []SkippedCard
+
+	// CorruptCopy is where a file that failed to decode was copied to,
+	// unless CorruptCopyErr says why it wasn't.
+	CorruptCopy    string
+	CorruptCopyErr error
+
+	Version    int
+	Newer      bool // written by a newer version; the deck is ReadOnly
+	Upgraded   bool // migrated from Version to SchemaVersion
+	Renumbered int  // cards given a new ID by FixDuplicateIDs
+
+	// Saved lists the files written to store an upgrade, a recovery or new
+	// IDs; SaveErr is why that failed.
+	Saved   []SavedFile
+	SaveErr error
+}
+
+// SkippedCard is a card of a damaged file that could not be read.
+type SkippedCard struct {
+	Position int // 1-based position in the card list or the trash
+	Trashed  bool
+	Err      error
+}
+
+// SavedFile is a deck file written by Save, or that would have been
+// written in a dry run.
+type SavedFile struct {
+	Path  string
+	Cards int
+	Trash int
+}
+
+// Load reads the open deck file(s). A missing or empty file gives an empty
+// deck. A damaged file is copied to <deck>.corrupt and as many cards as
+// possible are read from it; only when none can be the error is returned.
+func (d *Deck) Load() (LoadReport, error) {
+	defer d.Reindex()
+	if len(d.FilePaths) > 1 {
+		return d.loadDecks()
+	}
+	file, err := d.loadFile()
+	return LoadReport{Files: []FileReport{file}}, err
+}
+
+func (d *Deck) loadFile() (FileReport, error) {
+	report := FileReport{Path: d.FilePath}
+	d.Flashcards = []Flashcard{}
+	d.Trash = nil
+	d.maxID = 0
+	if _, err := os.Stat(d.FilePath); errors.Is(err, os.ErrNotExist) {
+		report.Missing = true
+		return report, nil
+	}
+
+	data, err := ioutil.ReadFile(d.FilePath)
+	if err != nil {
+		report.ReadErr = err
+		return report, err
+	}
+	if len(data) == 0 {
+		report.Empty = true
+		return report, nil
+	}
+
+	version, err := d.decode(data)
+	if err != nil {
+		report.DecodeErr = err
+		report.CorruptCopy, report.CorruptCopyErr = d.keepCorruptCopy(data)
+		var recoverErr error
+		version, report.Total, report.Skipped, recoverErr = d.recoverCards(data)
+		if recoverErr != nil {
+			d.Flashcards = []Flashcard{}
+			d.Trash = nil
+			return report, err
+		}
+		report.Recovered = true
+	}
+
+	for i, card := range d.Flashcards {
+		if card.ID > d.maxID {
+			d.maxID = card.ID
+		}
+		if card.EaseFactor == 0 {
+			d.Flashcards[i].EaseFactor = 2.5
+		}
+		d.Flashcards[i].Difficulty = strings.ToLower(strings.TrimSpace(card.Difficulty))
+		if !IsDifficulty(d.Flashcards[i].Difficulty) {
+			d.Flashcards[i].Difficulty = DifficultyMedium
+		}
+	}
+	report.Version = version
+	needsSave := report.Recovered
+	switch {
+	case version > SchemaVersion:
+		report.Newer = true
+		d.ReadOnly = true
+	case version < SchemaVersion:
+		d.migrate(version)
+		report.Upgraded = true
+		needsSave = true
+	}
+	if report.Renumbered = d.FixDuplicateIDs(); report.Renumbered > 0 {
+		needsSave = true
+	}
+	// Without a .corrupt copy the damaged original is all that is left of
+	// the cards that could not be recovered, so it isn't replaced on load.
+	if needsSave && !d.ReadOnly && report.CorruptCopyErr == nil {
+		report.Saved, report.SaveErr = d.Save()
+	}
+	report.Cards = len(d.Flashcards)
+	return report, nil
+}
+
+// loadDecks loads every deck of FilePaths and puts their cards together,
+// remembering each card's file so Save writes it back there. Cards whose ID
+// is already used by a card of an earlier deck get a fresh ID in memory
+// only; their file keeps the original one.
+func (d *Deck) loadDecks() (LoadReport, error) {
+	report := LoadReport{}
+	d.Flashcards = []Flashcard{}
+	d.Trash = nil
+	var firstErr error
+	for _, path := range d.FilePaths {
+		other := &Deck{FilePath: path, DryRun: d.DryRun, ReadOnly: d.ReadOnly}
+		file, err := other.loadFile()
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		report.Files = append(report.Files, file)
+		d.ReadOnly = d.ReadOnly || other.ReadOnly
+		d.SetDeckScheduler(path, other.schedulers[path])
+		for _, card := range other.Flashcards {
+			card.sourceFile = path
+			d.Flashcards = append(d.Flashcards, card)
+		}
+		for _, card := range other.Trash {
+			card.sourceFile = path
+			d.Trash = append(d.Trash, card)
+		}
+	}
+
+	d.maxID = 0
+	for _, card := range d.Flashcards {
+		if card.ID > d.maxID {
+			d.maxID = card.ID
+		}
+	}
+	seen := make(map[int]bool, len(d.Flashcards))
+	for i := range d.Flashcards {
+		card := &d.Flashcards[i]
+		if seen[card.ID] {
+			card.fileID = card.ID
+			card.ID = d.NextID()
+			report.Renumbered++
+		}
+		seen[card.ID] = true
+	}
+	return report, firstErr
+}
+
+// decode reads the cards from either file format into Flashcards and
+// returns the format version found.
+func (d *Deck) decode(data []byte) (int, error) {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		return 1, json.Unmarshal(trimmed, &d.Flashcards)
+	}
+	var file deckFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return 0, err
+	}
+	if file.Version == 0 {
+		return 0, errors.New("missing \"version\"")
+	}
+	d.Flashcards = file.Cards
+	if d.Flashcards == nil {
+		d.Flashcards = []Flashcard{}
+	}
+	d.Trash = file.Trash
+	d.SetDeckScheduler(d.FilePath, file.Scheduler)
+	return file.Version, nil
+}
+
+// recoverCards decodes a deck that failed to load one card at a time,
+// keeping the cards that parse and reporting the position of each one that
+// doesn't. It returns the file format and the number of cards in the file,
+// and fails only when the file isn't a list of JSON objects at all.
+func (d *Deck) recoverCards(data []byte) (int, int, []SkippedCard, error) {
+	var file struct {
+		Version   int               `json:"version"`
+		Scheduler string            `json:"scheduler"`
+		Cards     []json.RawMessage `json:"cards"`
+		Trash     []json.RawMessage `json:"trash"`
+	}
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		file.Version = 1
+		if err := json.Unmarshal(trimmed, &file.Cards); err != nil {
+			return 0, 0, nil, err
+		}
+	} else if err := json.Unmarshal(data, &file); err != nil {
+		return 0, 0, nil, err
+	} else if file.Version == 0 {
+		return 0, 0, nil, errors.New("missing \"version\"")
+	}
+
+	var skipped []SkippedCard
+	d.Flashcards = []Flashcard{}
+	for i, raw := range file.Cards {
+		var card Flashcard
+		if err := json.Unmarshal(raw, &card); err != nil {
+			skipped = append(skipped, SkippedCard{Position: i + 1, Err: err})
+			continue
+		}
+		d.Flashcards = append(d.Flashcards, card)
+	}
+	d.Trash = nil
+	for i, raw := range file.Trash {
+		var card Flashcard
+		if err := json.Unmarshal(raw, &card); err != nil {
+			skipped = append(skipped, SkippedCard{Position: i + 1, Trashed: true, Err: err})
+			continue
+		}
+		d.Trash = append(d.Trash, card)
+	}
+	d.SetDeckScheduler(d.FilePath, file.Scheduler)
+	return file.Version, len(file.Cards), skipped, nil
+}
+
+// keepCorruptCopy writes the deck file that failed to load to
+// <deck>.corrupt, so cards that can't be recovered are not lost once the
+// deck is saved again. It returns the path written, or "" in a dry run.
+func (d *Deck) keepCorruptCopy(data []byte) (string, error) {
+	if d.DryRun {
+		return "", nil
+	}
+	path := d.FilePath + ".corrupt"
+	return path, ioutil.WriteFile(path, data, 0644)
+}
+
+// migrate upgrades cards loaded from an older file format, one version at a
+// time. Defaults for new fields of future formats belong here.
+func (d *Deck) migrate(from int) {
+	for version := from; version < SchemaVersion; version++ {
+		switch version {
+		case 1:
+			// Version 2 only wrapped the card list; the cards are unchanged.
+		case 2:
+			// Version 3 added the optional trash; older files have none.
+		}
+	}
+}
+
+// FixDuplicateIDs gives every card whose ID was already used by an earlier
+// card a fresh ID, so stat updates by ID always reach the right card. It
+// returns the number of cards renumbered and expects maxID to be current.
+func (d *Deck) FixDuplicateIDs() int {
+	seen := make(map[int]bool, len(d.Flashcards))
+	fixed := 0
+	for i := range d.Flashcards {
+		id := d.Flashcards[i].ID
+		if seen[id] || id <= 0 {
+			d.Flashcards[i].ID = d.NextID()
+			fixed++
+		}
+		seen[d.Flashcards[i].ID] = true
+	}
+	return fixed
+}
+
+// Save writes the open deck file(s) and returns the files written, or in a
+// dry run the files that would have been. On error the files written
+// before it are still returned.
+func (d *Deck) Save() ([]SavedFile, error) {
+	if d.ReadOnly {
+		return nil, fmt.Errorf("'%s' is read-only because it was written by a newer version of the app", d.FilePath)
+	}
+	if len(d.FilePaths) <= 1 {
+		saved, err := d.writeDeck(d.FilePath, d.Flashcards, d.Trash)
+		if err != nil {
+			return nil, err
+		}
+		return []SavedFile{saved}, nil
+	}
+
+	// With several decks open every card goes back to the file it came
+	// from; new cards go to the first deck.
+	cards := make(map[string][]Flashcard)
+	trash := make(map[string][]Flashcard)
+	for _, card := range d.Flashcards {
+		path := d.CardFile(card)
+		if card.fileID != 0 {
+			card.ID = card.fileID
+		}
+		cards[path] = append(cards[path], card)
+	}
+	for _, card := range d.Trash {
+		path := d.CardFile(card)
+		trash[path] = append(trash[path], card)
+	}
+	saved := []SavedFile{}
+	for _, path := range d.FilePaths {
+		deckCards := cards[path]
+		if deckCards == nil {
+			deckCards = []Flashcard{}
+		}
+		file, err := d.writeDeck(path, deckCards, trash[path])
+		if err != nil {
+			return saved, err
+		}
+		saved = append(saved, file)
+	}
+	return saved, nil
+}
+
+// CardFile returns the deck file a card is saved to.
+func (d *Deck) CardFile(card Flashcard) string {
+	for _, path := range d.FilePaths {
+		if card.sourceFile == path {
+			return path
+		}
+	}
+	return d.FilePath
+}
+
+// writeDeck writes cards and trash to the deck file at path.
+func (d *Deck) writeDeck(path string, cards, trash []Flashcard) (SavedFile, error) {
+	saved := SavedFile{Path: path, Cards: len(cards), Trash: len(trash)}
+	if d.DryRun {
+		return saved, nil
+	}
+	data, err := json.MarshalIndent(deckFile{Version: SchemaVersion, Scheduler: d.schedulers[path], Cards: cards, Trash: trash}, "", "  ")
+	if err != nil {
+		return saved, fmt.Errorf("encoding '%s': %w", path, err)
+	}
+	if err := WriteFileAtomic(path, data); err != nil {
+		return saved, fmt.Errorf("writing '%s': %w", path, err)
+	}
+	return saved, nil
+}
+
+// WriteFileAtomic writes data to a temp file next to path and renames it
+// over path, so a crash mid-write never leaves a truncated deck. The previous
+// file is copied to path+".bak" first as a recovery point.
+func WriteFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath) // no-op once the rename succeeded
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpPath, 0644); err != nil {
+		return err
+	}
+
+	if previous, err := ioutil.ReadFile(path); err == nil {
+		if err := ioutil.WriteFile(path+".bak", previous, 0644); err != nil {
+			return fmt.Errorf("could not write backup: %w", err)
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	return os.Rename(tmpPath, path)
+}
+
+// DeckScheduler returns the default scheduler stored in the deck file at
+// path, or "" if it has none.
+func (d *Deck) DeckScheduler(path string) string {
+	return d.schedulers[path]
+}
+
+// SetDeckScheduler records the scheduler stored in the deck file at path,
+// which is written back with the deck.
+func (d *Deck) SetDeckScheduler(path, scheduler string) {
+	if d.schedulers == nil {
+		d.schedulers = map[string]string{}
+	}
+	d.schedulers[path] = scheduler
+}
@@ -0,0 +1,252 @@
+package deck
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// writeFile writes a deck file with the given content to a temporary
+// directory and returns its path.
+func writeFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "flashcards.json")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadFixesDuplicateIDs(t *testing.T) {
+	path := writeFile(t, `{"version":3,"cards":[
+		{"id":1,"question":"a","answer":"1"},
+		{"id":2,"question":"b","answer":"2"},
+		{"id":2,"question":"c","answer":"3"},
+		{"id":0,"question":"d","answer":"4"},
+		{"id":5,"question":"e","answer":"5"}
+	]}`)
+
+	d := New(path)
+	report, err := d.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got := report.Files[0].Renumbered; got != 2 {
+		t.Errorf("Renumbered = %d, want 2", got)
+	}
+
+	seen := map[int]string{}
+	for _, card := range d.Flashcards {
+		if card.ID <= 0 {
+			t.Errorf("card %q has ID %d", card.Question, card.ID)
+		}
+		if other, ok := seen[card.ID]; ok {
+			t.Errorf("cards %q and %q share ID %d", other, card.Question, card.ID)
+		}
+		seen[card.ID] = card.Question
+	}
+	if got := d.Flashcards[0].ID; got != 1 {
+		t.Errorf("first card ID = %d, want it kept as 1", got)
+	}
+	if got := d.NextID(); got != 8 {
+		t.Errorf("NextID() = %d, want 8 after the cards renumbered to 6 and 7", got)
+	}
+
+	// The new IDs were saved, so loading again renumbers nothing.
+	again := New(path)
+	report, err = again.Load()
+	if err != nil {
+		t.Fatalf("second Load: %v", err)
+	}
+	if got := report.Files[0].Renumbered; got != 0 {
+		t.Errorf("second load Renumbered = %d, want 0", got)
+	}
+	for i, card := range again.Flashcards {
+		if card.ID != d.Flashcards[i].ID {
+			t.Errorf("card %q reloaded with ID %d, want %d", card.Question, card.ID, d.Flashcards[i].ID)
+		}
+	}
+}
+
+// readFile returns the content of path, failing the test if it can't.
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestWriteFileAtomicKeepsBackup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flashcards.json")
+	for _, content := range []string{"first", "second", "third"} {
+		if err := WriteFileAtomic(path, []byte(content)); err != nil {
+			t.Fatalf("WriteFileAtomic(%q): %v", content, err)
+		}
+	}
+	if got := readFile(t, path); got != "third" {
+		t.Errorf("file = %q, want %q", got, "third")
+	}
+	if got := readFile(t, path+".bak"); got != "second" {
+		t.Errorf(".bak = %q, want %q", got, "second")
+	}
+}
+
+func TestWriteFileAtomicFailure(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("read-only directories don't prevent writes on Windows")
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "flashcards.json")
+	for _, content := range []string{"first", "second"} {
+		if err := WriteFileAtomic(path, []byte(content)); err != nil {
+			t.Fatalf("WriteFileAtomic(%q): %v", content, err)
+		}
+	}
+
+	if err := os.Chmod(dir, 0555); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chmod(dir, 0755)
+	if probe, err := os.CreateTemp(dir, "probe-*"); err == nil {
+		probe.Close()
+		os.Remove(probe.Name())
+		t.Skip("the read-only directory is still writable, e.g. when running as root")
+	}
+
+	if err := WriteFileAtomic(path, []byte("third")); err == nil {
+		t.Fatal("WriteFileAtomic into a read-only directory succeeded")
+	}
+	if got := readFile(t, path); got != "second" {
+		t.Errorf("file = %q after the failed write, want %q", got, "second")
+	}
+	if got := readFile(t, path+".bak"); got != "first" {
+		t.Errorf(".bak = %q after the failed write, want %q", got, "first")
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("directory holds %d files after the failed write, want the deck and its .bak", len(entries))
+	}
+}
+
+func TestLoadRecoversCards(t *testing.T) {
+	content := `{"version":3,"cards":[
+		{"id":1,"question":"a","answer":"1"},
+		{"id":"two","question":"b","answer":"2"},
+		{"id":3,"question":"c","answer":"3"}
+	],"trash":[
+		{"id":4,"question":"d","answer":["4"]}
+	]}`
+	path := writeFile(t, content)
+
+	d := New(path)
+	report, err := d.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	file := report.Files[0]
+	if file.DecodeErr == nil || !file.Recovered {
+		t.Fatalf("DecodeErr = %v, Recovered = %v; want a decode error and a recovery", file.DecodeErr, file.Recovered)
+	}
+	if file.Cards != 2 || file.Total != 3 {
+		t.Errorf("recovered %d of %d cards, want 2 of 3", file.Cards, file.Total)
+	}
+	if len(file.Skipped) != 2 {
+		t.Fatalf("Skipped = %+v, want the second card and the trashed one", file.Skipped)
+	}
+	if skipped := file.Skipped[0]; skipped.Position != 2 || skipped.Trashed || skipped.Err == nil {
+		t.Errorf("Skipped[0] = %+v, want card 2 of the card list with its error", skipped)
+	}
+	if skipped := file.Skipped[1]; skipped.Position != 1 || !skipped.Trashed || skipped.Err == nil {
+		t.Errorf("Skipped[1] = %+v, want card 1 of the trash with its error", skipped)
+	}
+	if len(d.Flashcards) != 2 || d.Flashcards[0].Question != "a" || d.Flashcards[1].Question != "c" {
+		t.Errorf("recovered cards %+v, want a and c", d.Flashcards)
+	}
+
+	if file.CorruptCopyErr != nil || file.CorruptCopy != path+".corrupt" {
+		t.Errorf("CorruptCopy = %q, %v; want %q", file.CorruptCopy, file.CorruptCopyErr, path+".corrupt")
+	}
+	if got := readFile(t, path+".corrupt"); got != content {
+		t.Errorf(".corrupt holds %q, want the damaged file", got)
+	}
+
+	// The recovered cards were saved, so the deck loads cleanly again.
+	if file.SaveErr != nil || len(file.Saved) != 1 {
+		t.Fatalf("Saved = %+v, %v; want the deck saved once", file.Saved, file.SaveErr)
+	}
+	again := New(path)
+	report, err = again.Load()
+	if err != nil || report.Files[0].DecodeErr != nil || len(again.Flashcards) != 2 {
+		t.Errorf("reloading: %v, %v, %d cards; want 2 cards without errors", err, report.Files[0].DecodeErr, len(again.Flashcards))
+	}
+}
+
+func TestLoadUnrecoverable(t *testing.T) {
+	path := writeFile(t, `{"version":3,"cards":[{"id":1,`)
+	d := New(path)
+	report, err := d.Load()
+	if err == nil {
+		t.Fatal("Load of a truncated file succeeded, want an error")
+	}
+	if file := report.Files[0]; file.Recovered || file.CorruptCopy == "" {
+		t.Errorf("Recovered = %v, CorruptCopy = %q; want no recovery but a copy", file.Recovered, file.CorruptCopy)
+	}
+	if len(d.Flashcards) != 0 {
+		t.Errorf("deck holds %d cards, want none", len(d.Flashcards))
+	}
+}
+
+func TestLoadReadOnlyDoesNotSave(t *testing.T) {
+	content := `[{"id":1,"question":"a","answer":"1"},{"id":1,"question":"b","answer":"2"}]`
+	path := writeFile(t, content)
+
+	d := New(path)
+	d.ReadOnly = true
+	report, err := d.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if file := report.Files[0]; !file.Upgraded || file.Renumbered != 1 || len(file.Saved) != 0 {
+		t.Errorf("Upgraded = %v, Renumbered = %d, Saved = %+v; want an upgrade and a renumbering in memory only", file.Upgraded, file.Renumbered, file.Saved)
+	}
+	if d.Flashcards[0].ID == d.Flashcards[1].ID {
+		t.Errorf("both cards kept ID %d", d.Flashcards[0].ID)
+	}
+	if got := readFile(t, path); got != content {
+		t.Errorf("the read-only load rewrote the deck: %q", got)
+	}
+}
+
+func TestLoadKeepsDamagedFileWithoutCopy(t *testing.T) {
+	content := `{"version":3,"cards":[{"id":1,"question":"a","answer":"1"},{"id":"two","question":"b","answer":"2"}]}`
+	path := writeFile(t, content)
+	// A directory in the way makes writing the .corrupt copy fail.
+	if err := os.Mkdir(path+".corrupt", 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	d := New(path)
+	report, err := d.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	file := report.Files[0]
+	if !file.Recovered || file.CorruptCopyErr == nil {
+		t.Fatalf("Recovered = %v, CorruptCopyErr = %v; want a recovery without a copy", file.Recovered, file.CorruptCopyErr)
+	}
+	if len(file.Saved) != 0 || file.SaveErr != nil {
+		t.Errorf("Saved = %+v, %v; want no save", file.Saved, file.SaveErr)
+	}
+	if got := readFile(t, path); got != content {
+		t.Errorf("the damaged file was replaced with %q", got)
+	}
+	if len(d.Flashcards) != 1 {
+		t.Errorf("deck holds %d cards, want the one recovered", len(d.Flashcards))
+	}
+}
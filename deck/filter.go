@@ -0,0 +1,294 @@
+package deck
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FilterByCategory returns a copy of the cards in the given category,
+// ignoring case, or of all cards when the filter is empty.
+func FilterByCategory(cards []Flashcard, category string) []Flashcard {
+	filtered := []Flashcard{}
+	for _, card := range cards {
+		if category == "" || strings.EqualFold(card.Category, category) {
+			filtered = append(filtered, card)
+		}
+	}
+	return filtered
+}
+
+// FilterByDifficulty returns the cards with the given difficulty.
+func FilterByDifficulty(cards []Flashcard, difficulty string) []Flashcard {
+	filtered := []Flashcard{}
+	for _, card := range cards {
+		if strings.EqualFold(card.Difficulty, difficulty) {
+			filtered = append(filtered, card)
+		}
+	}
+	return filtered
+}
+
+// FilterByTag returns the cards carrying the given tag, ignoring case.
+func FilterByTag(cards []Flashcard, tag string) []Flashcard {
+	filtered := []Flashcard{}
+	for _, card := range cards {
+		if ContainsFold(card.Tags, tag) {
+			filtered = append(filtered, card)
+		}
+	}
+	return filtered
+}
+
+// OnlyFlagged keeps the cards that were flagged as wrong or confusing.
+func OnlyFlagged(cards []Flashcard) []Flashcard {
+	flagged := []Flashcard{}
+	for _, card := range cards {
+		if card.Flagged {
+			flagged = append(flagged, card)
+		}
+	}
+	return flagged
+}
+
+// OnlyMistakes keeps the cards whose most recent review or quiz answer was
+// wrong. Cards never reviewed are left out.
+func OnlyMistakes(cards []Flashcard) []Flashcard {
+	mistakes := []Flashcard{}
+	for _, card := range cards {
+		if card.LastCorrect != nil && !*card.LastCorrect {
+			mistakes = append(mistakes, card)
+		}
+	}
+	return mistakes
+}
+
+// DailyLimit keeps, in order, at most newLeft cards never reviewed and
+// reviewsLeft cards reviewed before. A negative count means no limit.
+func DailyLimit(cards []Flashcard, newLeft, reviewsLeft int) []Flashcard {
+	kept := []Flashcard{}
+	for _, card := range cards {
+		if card.TimesReviewed == 0 && newLeft >= 0 {
+			if newLeft == 0 {
+				continue
+			}
+			newLeft--
+		} else if card.TimesReviewed > 0 && reviewsLeft >= 0 {
+			if reviewsLeft == 0 {
+				continue
+			}
+			reviewsLeft--
+		}
+		kept = append(kept, card)
+	}
+	return kept
+}
+
+// Predicate reports whether a card matches one term of a search query.
+type Predicate func(Flashcard) bool
+
+// ParseQuery turns a search query into predicates that must all match.
+// Terms like "category:French", "question:être" or "accuracy:<50" are
+// scoped to a field; text fields match by substring, the numeric fields
+// id, reviewed, correct and accuracy by comparison (<, <=, >, >=, =).
+// Values may be quoted to include spaces. Without any field prefix the
+// whole query is a plain substring search.
+func ParseQuery(query string) ([]Predicate, error) {
+	terms := splitQuery(query)
+	scoped := false
+	for _, term := range terms {
+		if field, _, ok := strings.Cut(term, ":"); ok && isQueryField(field) {
+			scoped = true
+			break
+		}
+	}
+	if !scoped {
+		return []Predicate{matchText(strings.TrimSpace(query))}, nil
+	}
+
+	predicates := []Predicate{}
+	for _, term := range terms {
+		field, value, ok := strings.Cut(term, ":")
+		if !ok || !isQueryField(field) {
+			predicates = append(predicates, matchText(term))
+			continue
+		}
+		predicate, err := fieldPredicate(strings.ToLower(field), value)
+		if err != nil {
+			return nil, err
+		}
+		predicates = append(predicates, predicate)
+	}
+	return predicates, nil
+}
+
+// splitQuery splits on whitespace but keeps double-quoted parts together.
+func splitQuery(query string) []string {
+	terms := []string{}
+	var current strings.Builder
+	inQuotes := false
+	for _, r := range query {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+		case r == ' ' && !inQuotes:
+			if current.Len() > 0 {
+				terms = append(terms, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if current.Len() > 0 {
+		terms = append(terms, current.String())
+	}
+	return terms
+}
+
+func isQueryField(field string) bool {
+	switch strings.ToLower(field) {
+	case "question", "answer", "category", "id", "reviewed", "correct", "accuracy":
+		return true
+	}
+	return false
+}
+
+// matchText matches a substring of the question, answers or category.
+func matchText(text string) Predicate {
+	needle := strings.ToLower(text)
+	return func(card Flashcard) bool {
+		fields := append([]string{card.Question, card.Answer, card.Category}, card.CorrectAnswers...)
+		for _, field := range fields {
+			if strings.Contains(strings.ToLower(field), needle) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func fieldPredicate(field, value string) (Predicate, error) {
+	needle := strings.ToLower(value)
+	switch field {
+	case "question":
+		return func(card Flashcard) bool {
+			return strings.Contains(strings.ToLower(card.Question), needle)
+		}, nil
+	case "answer":
+		return func(card Flashcard) bool {
+			for _, answer := range append([]string{card.Answer}, card.CorrectAnswers...) {
+				if strings.Contains(strings.ToLower(answer), needle) {
+					return true
+				}
+			}
+			return false
+		}, nil
+	case "category":
+		return func(card Flashcard) bool {
+			return strings.Contains(strings.ToLower(card.Category), needle)
+		}, nil
+	}
+
+	compare, err := parseComparison(value)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", field, err)
+	}
+	switch field {
+	case "id":
+		return func(card Flashcard) bool { return compare(float64(card.ID)) }, nil
+	case "reviewed":
+		return func(card Flashcard) bool { return compare(float64(card.TimesReviewed)) }, nil
+	case "correct":
+		return func(card Flashcard) bool { return compare(float64(card.TimesCorrect)) }, nil
+	default: // accuracy
+		return func(card Flashcard) bool {
+			if card.TimesReviewed == 0 {
+				return false
+			}
+			return compare(float64(card.TimesCorrect) / float64(card.TimesReviewed) * 100)
+		}, nil
+	}
+}
+
+// parseComparison parses values like "<50", ">=3" or "7" into a check.
+func parseComparison(value string) (func(float64) bool, error) {
+	op := "="
+	for _, candidate := range []string{"<=", ">=", "<", ">", "="} {
+		if strings.HasPrefix(value, candidate) {
+			op = candidate
+			value = value[len(candidate):]
+			break
+		}
+	}
+	number, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+	if err != nil {
+		return nil, fmt.Errorf("'%s' is not a number", value)
+	}
+	switch op {
+	case "<":
+		return func(v float64) bool { return v < number }, nil
+	case "<=":
+		return func(v float64) bool { return v <= number }, nil
+	case ">":
+		return func(v float64) bool { return v > number }, nil
+	case ">=":
+		return func(v float64) bool { return v >= number }, nil
+	default:
+		return func(v float64) bool { return v == number }, nil
+	}
+}
+
+// Search returns the cards matching every term of the query, by ID.
+func (d *Deck) Search(query string) ([]Flashcard, error) {
+	predicates, err := ParseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	matches := []Flashcard{}
+	for _, card := range d.Flashcards {
+		matched := true
+		for _, predicate := range predicates {
+			if !predicate(card) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			matches = append(matches, card)
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].ID < matches[j].ID
+	})
+	return matches, nil
+}
+
+// QuestionTerms returns the query terms that match against the question
+// text, for highlighting in search results.
+func QuestionTerms(query string) []string {
+	terms := splitQuery(query)
+	scoped := false
+	for _, term := range terms {
+		if field, _, ok := strings.Cut(term, ":"); ok && isQueryField(field) {
+			scoped = true
+			break
+		}
+	}
+	if !scoped {
+		return []string{strings.TrimSpace(query)}
+	}
+
+	needles := []string{}
+	for _, term := range terms {
+		field, value, ok := strings.Cut(term, ":")
+		switch {
+		case !ok || !isQueryField(field):
+			needles = append(needles, term)
+		case strings.EqualFold(field, "question"):
+			needles = append(needles, value)
+		}
+	}
+	return needles
+}
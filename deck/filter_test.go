@@ -0,0 +1,120 @@
+package deck
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{}},
+		{"capital France", []string{"capital", "France"}},
+		{"  spaced   out  ", []string{"spaced", "out"}},
+		{`category:"World History" reviewed:>2`, []string{"category:World History", "reviewed:>2"}},
+		{`"two words"`, []string{"two words"}},
+	}
+	for _, tt := range tests {
+		if got := splitQuery(tt.query); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitQuery(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestParseComparison(t *testing.T) {
+	tests := []struct {
+		value string
+		in    float64
+		want  bool
+	}{
+		{"<50", 49, true},
+		{"<50", 50, false},
+		{"<=50", 50, true},
+		{">3", 3, false},
+		{">3", 4, true},
+		{">=3", 3, true},
+		{"=7", 7, true},
+		{"7", 7, true},
+		{"7", 8, false},
+		{"<50%", 40, true},
+		{">=2.5", 2.5, true},
+	}
+	for _, tt := range tests {
+		compare, err := parseComparison(tt.value)
+		if err != nil {
+			t.Errorf("parseComparison(%q): %v", tt.value, err)
+			continue
+		}
+		if got := compare(tt.in); got != tt.want {
+			t.Errorf("parseComparison(%q)(%v) = %v, want %v", tt.value, tt.in, got, tt.want)
+		}
+	}
+
+	for _, value := range []string{"", "<", "abc", ">=x", "5x"} {
+		if _, err := parseComparison(value); err == nil {
+			t.Errorf("parseComparison(%q) succeeded, want an error", value)
+		}
+	}
+}
+
+func TestParseQuery(t *testing.T) {
+	cards := []Flashcard{
+		{ID: 1, Question: "Capital of France?", Answer: "Paris", Category: "Geography", TimesReviewed: 4, TimesCorrect: 1},
+		{ID: 2, Question: "Capital of Spain?", Answer: "Madrid", Category: "Geography", TimesReviewed: 2, TimesCorrect: 2},
+		{ID: 3, Question: "être: to be", Answer: "to be", CorrectAnswers: []string{"to be", "be"}, Category: "French"},
+		{ID: 4, Question: "Year of the French Revolution?", Answer: "1789", Category: "World History", TimesReviewed: 10, TimesCorrect: 9},
+	}
+	tests := []struct {
+		name  string
+		query string
+		want  []int
+	}{
+		{"unscoped substring", "capital", []int{1, 2}},
+		{"unscoped matches the category", "french", []int{3, 4}},
+		{"unscoped keeps the whole query", "of France", []int{1}},
+		{"unknown field is plain text", "note:x", nil},
+		{"scoped category", "category:french", []int{3}},
+		{"scoped question", "question:être", []int{3}},
+		{"scoped answer matches correct answers", "answer:be", []int{3}},
+		{"quoted value", `category:"world history"`, []int{4}},
+		{"field names ignore case", "Category:Geography", []int{1, 2}},
+		{"scoped and plain term", "category:geography spain", []int{2}},
+		{"id", "id:>=3", []int{3, 4}},
+		{"reviewed", "reviewed:>2", []int{1, 4}},
+		{"correct", "correct:2", []int{2}},
+		{"accuracy", "accuracy:<50", []int{1}},
+		{"accuracy skips unreviewed cards", "accuracy:<=100", []int{1, 2, 4}},
+		{"terms combine", "category:geography accuracy:>=50", []int{2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			predicates, err := ParseQuery(tt.query)
+			if err != nil {
+				t.Fatalf("ParseQuery(%q): %v", tt.query, err)
+			}
+			var got []int
+			for _, card := range cards {
+				matched := true
+				for _, predicate := range predicates {
+					matched = matched && predicate(card)
+				}
+				if matched {
+					got = append(got, card.ID)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseQuery(%q) matched %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseQueryNonNumeric(t *testing.T) {
+	for _, query := range []string{"reviewed:many", "accuracy:<high", "category:geo id:x"} {
+		if _, err := ParseQuery(query); err == nil {
+			t.Errorf("ParseQuery(%q) succeeded, want an error", query)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package deck
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// lockWait is how long a second instance waits for the deck lock before
+// giving up; staleLockAge is the age after which a lock is ignored when its
+// process can't be checked.
+const (
+	lockWait     = 3 * time.Second
+	staleLockAge = 24 * time.Hour
+)
+
+// StaleLock is a lock left behind by a process that is gone, which Lock
+// took over.
+type StaleLock struct {
+	Path string
+	PID  int
+}
+
+// LockPath returns the lock file of the deck at path.
+func LockPath(path string) string {
+	return path + ".lock"
+}
+
+// Lock locks every open deck, so a second instance on the same deck can't
+// overwrite its changes, and returns the stale locks it took over. If one
+// of them can't be locked, the locks already taken are released again.
+func (d *Deck) Lock() ([]StaleLock, error) {
+	var stale []StaleLock
+	for _, path := range d.Paths() {
+		locked, takenOver, err := lockDeck(path)
+		stale = append(stale, takenOver...)
+		if err != nil {
+			d.Unlock()
+			return stale, err
+		}
+		if locked {
+			d.lockedPaths = append(d.lockedPaths, path)
+		}
+	}
+	return stale, nil
+}
+
+// lockDeck creates the lock file of the deck at path holding this process's
+// PID and reports whether it did. A lock held by another process is waited
+// for briefly; one left behind by a process that is gone is removed. Decks
+// in a missing directory are not locked, as they can't be saved either.
+func lockDeck(path string) (bool, []StaleLock, error) {
+	var stale []StaleLock
+	deadline := time.Now().Add(lockWait)
+	for {
+		file, err := os.OpenFile(LockPath(path), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			fmt.Fprintf(file, "%d\n", os.Getpid())
+			return true, stale, file.Close()
+		}
+		if errors.Is(err, os.ErrNotExist) {
+			return false, stale, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return false, stale, err
+		}
+
+		pid, isStale := staleLock(LockPath(path))
+		if isStale {
+			pid, takenOver, err := takeOverLock(path)
+			if err != nil {
+				return false, stale, err
+			}
+			if takenOver {
+				stale = append(stale, StaleLock{Path: path, PID: pid})
+			}
+			continue
+		}
+		if time.Now().After(deadline) {
+			return false, stale, fmt.Errorf("'%s' is open in another flashcards process (PID %d); close it first, or delete '%s' if that process is gone", path, pid, LockPath(path))
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+}
+
+// takeOverLock removes the stale lock of the deck at path and returns the
+// PID it held. The lock file is first renamed out of the way and checked
+// again, so when another waiter removed it and took the lock in the
+// meantime, that new lock is put back instead of removed.
+func takeOverLock(path string) (int, bool, error) {
+	lockPath := LockPath(path)
+	moved := fmt.Sprintf("%s.stale-%d", lockPath, os.Getpid())
+	if err := os.Rename(lockPath, moved); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, false, nil
+		}
+		return 0, false, err
+	}
+	pid, isStale := staleLock(moved)
+	if isStale {
+		return pid, true, os.Remove(moved)
+	}
+	err := os.Link(moved, lockPath)
+	os.Remove(moved)
+	if err != nil {
+		return 0, false, fmt.Errorf("could not restore the lock of PID %d on '%s': %w", pid, path, err)
+	}
+	return 0, false, nil
+}
+
+// staleLock reports the PID in the lock file at lockPath and whether the
+// lock can be taken over: its process no longer runs, the file can't be
+// read, or the process can't be checked and the lock is older than
+// staleLockAge.
+func staleLock(lockPath string) (int, bool) {
+	info, err := os.Stat(lockPath)
+	if err != nil {
+		return 0, errors.Is(err, os.ErrNotExist)
+	}
+	data, err := ioutil.ReadFile(lockPath)
+	if err != nil {
+		return 0, false
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		// A lock being written right now is still empty; only a lock that
+		// stays unreadable is stale.
+		return 0, time.Since(info.ModTime()) > time.Second
+	}
+	alive, checked := processAlive(pid)
+	if !checked {
+		return pid, time.Since(info.ModTime()) > staleLockAge
+	}
+	return pid, !alive
+}
+
+// processAlive reports whether a process with the given PID exists, and
+// whether that could be checked at all. Elsewhere than on Windows signal 0
+// probes the process; on Windows finding it proves nothing.
+func processAlive(pid int) (bool, bool) {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false, true
+	}
+	if runtime.GOOS == "windows" {
+		return true, false
+	}
+	err = process.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, os.ErrPermission), true
+}
+
+// Unlock removes the lock files taken by Lock. It tries all of them and
+// returns the errors of those it could not remove.
+func (d *Deck) Unlock() error {
+	var errs []error
+	for _, path := range d.lockedPaths {
+		if err := os.Remove(LockPath(path)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+	d.lockedPaths = nil
+	return errors.Join(errs...)
+}
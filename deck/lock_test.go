@@ -0,0 +1,121 @@
+package deck
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// exitedPID returns the PID of a process that has already exited.
+func exitedPID(t *testing.T) int {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	return cmd.Process.Pid
+}
+
+// writeLock writes a lock file holding pid for the deck at path, last
+// modified age ago.
+func writeLock(t *testing.T, path string, pid int, age time.Duration) {
+	t.Helper()
+	if err := os.WriteFile(LockPath(path), []byte(fmt.Sprintf("%d\n", pid)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	modified := time.Now().Add(-age)
+	if err := os.Chtimes(LockPath(path), modified, modified); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStaleLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flashcards.json")
+	tests := []struct {
+		name  string
+		pid   int
+		age   time.Duration
+		stale bool
+	}{
+		{"live process", os.Getpid(), 0, false},
+		{"live process, old lock", os.Getpid(), 2 * staleLockAge, false},
+		{"exited process", exitedPID(t), 0, true},
+	}
+	for _, tt := range tests {
+		writeLock(t, path, tt.pid, tt.age)
+		pid, stale := staleLock(LockPath(path))
+		if pid != tt.pid || stale != tt.stale {
+			t.Errorf("%s: staleLock() = %d, %v; want %d, %v", tt.name, pid, stale, tt.pid, tt.stale)
+		}
+	}
+
+	if err := os.WriteFile(LockPath(path), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, stale := staleLock(LockPath(path)); stale {
+		t.Error("a lock being written right now is stale")
+	}
+}
+
+func TestTakeOverLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flashcards.json")
+
+	// A lock taken by a live process after the caller saw a stale one is
+	// put back untouched.
+	writeLock(t, path, os.Getpid(), 0)
+	if _, takenOver, err := takeOverLock(path); err != nil || takenOver {
+		t.Fatalf("takeOverLock of a live lock = %v, %v; want it left alone", takenOver, err)
+	}
+	if got := readFile(t, LockPath(path)); got != fmt.Sprintf("%d\n", os.Getpid()) {
+		t.Errorf("lock file holds %q after takeOverLock, want the live PID", got)
+	}
+
+	pid := exitedPID(t)
+	writeLock(t, path, pid, 0)
+	got, takenOver, err := takeOverLock(path)
+	if err != nil || !takenOver || got != pid {
+		t.Fatalf("takeOverLock of a stale lock = %d, %v, %v; want %d taken over", got, takenOver, err, pid)
+	}
+	if _, err := os.Stat(LockPath(path)); !os.IsNotExist(err) {
+		t.Errorf("the stale lock is still there: %v", err)
+	}
+
+	// A lock removed by another waiter meanwhile is nothing to take over.
+	if _, takenOver, err := takeOverLock(path); err != nil || takenOver {
+		t.Errorf("takeOverLock without a lock = %v, %v; want nothing taken over", takenOver, err)
+	}
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("files left behind: %v", entries)
+	}
+}
+
+func TestLockTakesOverStaleLock(t *testing.T) {
+	path := writeFile(t, `{"version":3,"cards":[]}`)
+	pid := exitedPID(t)
+	writeLock(t, path, pid, 0)
+
+	d := New(path)
+	stale, err := d.Lock()
+	if err != nil {
+		t.Fatalf("Lock: %v", err)
+	}
+	if len(stale) != 1 || stale[0].PID != pid {
+		t.Errorf("Lock took over %+v, want the lock of PID %d", stale, pid)
+	}
+	if got := readFile(t, LockPath(path)); got != fmt.Sprintf("%d\n", os.Getpid()) {
+		t.Errorf("lock file holds %q, want this process's PID", got)
+	}
+	if err := d.Unlock(); err != nil {
+		t.Errorf("Unlock: %v", err)
+	}
+	if _, err := os.Stat(LockPath(path)); !os.IsNotExist(err) {
+		t.Errorf("the lock is still there after Unlock: %v", err)
+	}
+}
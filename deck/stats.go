@@ -0,0 +1,113 @@
+package deck
+
+import (
+	"sort"
+	"strings"
+)
+
+// Categories returns the distinct categories of the deck, sorted.
+func (d *Deck) Categories() []string {
+	categoryMap := make(map[string]bool)
+	for _, card := range d.Flashcards {
+		categoryMap[card.Category] = true
+	}
+
+	categories := make([]string, 0, len(categoryMap))
+	for category := range categoryMap {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+	return categories
+}
+
+// TagCount is a tag together with the number of cards carrying it.
+type TagCount struct {
+	Tag   string
+	Cards int
+}
+
+// Tags returns every distinct tag in the deck with its card count, sorted
+// by name. Tags differing only in case count as one, shown as first seen.
+func (d *Deck) Tags() []TagCount {
+	index := make(map[string]int)
+	tags := []TagCount{}
+	for _, card := range d.Flashcards {
+		for _, tag := range card.Tags {
+			key := strings.ToLower(tag)
+			if i, ok := index[key]; ok {
+				tags[i].Cards++
+				continue
+			}
+			index[key] = len(tags)
+			tags = append(tags, TagCount{Tag: tag, Cards: 1})
+		}
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		return strings.ToLower(tags[i].Tag) < strings.ToLower(tags[j].Tag)
+	})
+	return tags
+}
+
+// CategoryStat aggregates the review statistics of one category.
+type CategoryStat struct {
+	Category string
+	Cards    int
+	Reviewed int
+	Correct  int
+}
+
+// Accuracy returns the share of correct reviews in percent, or 0 when the
+// category has not been reviewed yet.
+func (c CategoryStat) Accuracy() float64 {
+	if c.Reviewed == 0 {
+		return 0
+	}
+	return (float64(c.Correct) / float64(c.Reviewed)) * 100
+}
+
+// CategoryStats returns per-category totals sorted by category name.
+func (d *Deck) CategoryStats() []CategoryStat {
+	statsByCategory := make(map[string]*CategoryStat)
+	for _, card := range d.Flashcards {
+		stat, ok := statsByCategory[card.Category]
+		if !ok {
+			stat = &CategoryStat{Category: card.Category}
+			statsByCategory[card.Category] = stat
+		}
+		stat.Cards++
+		stat.Reviewed += card.TimesReviewed
+		stat.Correct += card.TimesCorrect
+	}
+
+	stats := make([]CategoryStat, 0, len(statsByCategory))
+	for _, stat := range statsByCategory {
+		stats = append(stats, *stat)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Category < stats[j].Category
+	})
+	return stats
+}
+
+// Totals are the review counts of the whole deck.
+type Totals struct {
+	Cards         int
+	Reviewed      int
+	Correct       int
+	NeverReviewed int
+	Hinted        int
+}
+
+// Totals adds up the review counts of all cards.
+func (d *Deck) Totals() Totals {
+	totals := Totals{Cards: len(d.Flashcards)}
+	for _, card := range d.Flashcards {
+		totals.Reviewed += card.TimesReviewed
+		totals.Correct += card.TimesCorrect
+		totals.Hinted += card.TimesHinted
+		if card.TimesReviewed == 0 {
+			totals.NeverReviewed++
+		}
+	}
+	return totals
+}
@@ -2,7 +2,10 @@ module flashcards-go
 
 go 1.24
 
-require github.com/pterm/pterm v0.12.80
+require (
+	github.com/pterm/pterm v0.12.80
+	golang.org/x/text v0.24.0
+)
 
 require (
 	atomicgo.dev/cursor v0.2.0 // indirect
@@ -16,5 +19,4 @@ require (
 	github.com/xo/terminfo v0.0.0-20220910002029-abceb7e1c41e // indirect
 	golang.org/x/sys v0.32.0 // indirect
 	golang.org/x/term v0.31.0 // indirect
-	golang.org/x/text v0.24.0 // indirect
 )
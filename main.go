@@ -1,548 +1,6230 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"encoding/csv"
 	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
+	"html"
 	"io/ioutil"
+	"math"
 	"math/rand"
 	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
+	"unicode"
+
+	"flashcards-go/deck"
 
 	"github.com/pterm/pterm"
+	"golang.org/x/text/unicode/norm"
 )
 
-type Flashcard struct {
-	ID             int        `json:"id"`
-	Question       string     `json:"question"`
-	Answer         string     `json:"answer"`
-	CorrectAnswers []string   `json:"correct_answers"`
-	Options        []string   `json:"options,omitempty"`
-	Category       string     `json:"category"`
-	CreatedAt      time.Time  `json:"created_at"`
-	LastReviewed   *time.Time `json:"last_reviewed,omitempty"`
-	TimesReviewed  int        `json:"times_reviewed"`
-	TimesCorrect   int        `json:"times_correct"`
-}
+// Flashcard is the card type of the deck package, used throughout main.
+type Flashcard = deck.Flashcard
+
+const (
+	schedulerNone    = "none"
+	schedulerSM2     = "sm2"
+	schedulerLeitner = "leitner"
+	schedulerFSRS    = "fsrs"
+)
 
+const (
+	revealInstant = "instant"
+	revealWait    = "wait"
+)
+
+// defaultDelayMs is the default pause after each quiz answer.
+const defaultDelayMs = 500
+
+// leitnerIntervals holds the review interval in days for boxes 1 to 5. The
+// number of boxes and their intervals can be changed with
+// -leitner-intervals.
+var leitnerIntervals = []int{1, 2, 4, 8, 16}
+
+// FlashcardApp is the interactive app around a deck: its study settings
+// and everything that prints or prompts.
 type FlashcardApp struct {
-	FilePath   string
-	Flashcards []Flashcard
-	maxID      int
+	*deck.Deck
+	SortBy          string
+	RepeatMissed    bool
+	Requeue         bool
+	Scheduler       string
+	MinInterval     time.Duration
+	MaxInterval     time.Duration
+	Summary         string
+	StableOptions   bool
+	NumericMC       bool
+	PartialCredit   float64
+	HideMastered    bool
+	PageSize        int
+	HideAnswers     bool
+	RevealStyle     string
+	Fuzzy           bool
+	FuzzyThreshold  float64
+	DueOnly         bool
+	MistakesOnly    bool
+	TagFilter       string
+	Weighted        bool
+	SessionLimit    int
+	Delay           time.Duration
+	TimePerQuestion time.Duration
+	RequireAll      bool
+	Reverse         bool
+	NoShuffle       bool
+	CaseSensitive   bool
+	NumericAnswers  bool
+	LastCategory    bool
+	Force           bool
+	ImportMode      string
+	NewPerDay       int
+	ReviewsPerDay   int
+
+	PostSaveHook        string
+	PostSaveHookTimeout time.Duration
+
+	lastSnapshot []Flashcard
+	inSession    bool
+	overdueFirst bool
+	reviewAll    bool // review every selected card, not only the due ones
+	leitnerOnly  bool // review only the cards scheduled with Leitner boxes
+
+	// sessionMu guards inSession and the cards' review stats while a
+	// session runs, as handleInterrupt saves them from the signal goroutine.
+	sessionMu sync.Mutex
 }
 
+// NewFlashcardApp loads the deck at filePath, or all decks of a
+// comma-separated list of paths.
 func NewFlashcardApp(filePath string) *FlashcardApp {
-	app := &FlashcardApp{
-		FilePath:   filePath,
-		Flashcards: []Flashcard{},
-		maxID:      0,
-	}
+	app := newDeckApp(filePath)
 	app.loadFlashcards()
 	return app
 }
 
-func (app *FlashcardApp) loadFlashcards() error {
-	if _, err := os.Stat(app.FilePath); errors.Is(err, os.ErrNotExist) {
-		pterm.Warning.Printf("Flashcard file '%s' not found. Starting with an empty set.\n", app.FilePath)
-		app.Flashcards = []Flashcard{}
-		app.maxID = 0
-		return nil
-	}
+// newDeckApp returns an app for the deck(s) in filePath without loading
+// them. Side files such as scores and history belong to the first deck.
+func newDeckApp(filePath string) *FlashcardApp {
+	return &FlashcardApp{Deck: deck.New(filePath)}
+}
 
-	data, err := ioutil.ReadFile(app.FilePath)
-	if err != nil {
-		pterm.Error.Printf("Error reading flashcard file '%s': %v\n", app.FilePath, err)
-		return err
+// openLockedDeck takes the lock of the deck(s) at filePath and loads them.
+// The caller releases the lock with releaseLock.
+func openLockedDeck(filePath string) (*FlashcardApp, error) {
+	app := newDeckApp(filePath)
+	if err := app.acquireLock(); err != nil {
+		return nil, err
 	}
+	app.loadFlashcards()
+	return app, nil
+}
 
-	if len(data) == 0 {
-		pterm.Warning.Printf("Flashcard file '%s' is empty. Starting with an empty set.\n", app.FilePath)
-		app.Flashcards = []Flashcard{}
-		app.maxID = 0
-		return nil
+// loadFlashcards loads the open deck(s) and reports what was found in each
+// file.
+func (app *FlashcardApp) loadFlashcards() error {
+	report, err := app.Load()
+	for _, file := range report.Files {
+		app.printFileReport(file)
+	}
+	if len(report.Files) > 1 {
+		if report.Renumbered > 0 {
+			pterm.Info.Printf("Gave %d cards other IDs for this session because another deck already uses theirs; their files keep the original IDs.\n", report.Renumbered)
+		}
+		pterm.Info.Printf("Loaded %d flashcards from %d decks.\n", len(app.Flashcards), len(report.Files))
 	}
+	return err
+}
 
-	err = json.Unmarshal(data, &app.Flashcards)
-	if err != nil {
-		pterm.Error.Printf("Error decoding flashcard JSON from '%s': %v\n", app.FilePath, err)
-		pterm.Warning.Println("Could not load existing cards. Starting with an empty set.")
-		app.Flashcards = []Flashcard{}
-		app.maxID = 0
-		return err
+// printFileReport prints what loading one deck file found and changed.
+func (app *FlashcardApp) printFileReport(file deck.FileReport) {
+	switch {
+	case file.Missing:
+		pterm.Warning.Printf("Flashcard file '%s' not found. Starting with an empty set.\n", file.Path)
+		return
+	case file.Empty:
+		pterm.Warning.Printf("Flashcard file '%s' is empty. Starting with an empty set.\n", file.Path)
+		return
+	case file.ReadErr != nil:
+		pterm.Error.Printf("Error reading flashcard file '%s': %v\n", file.Path, file.ReadErr)
+		return
 	}
 
-	app.maxID = 0
-	for _, card := range app.Flashcards {
-		if card.ID > app.maxID {
-			app.maxID = card.ID
+	if file.DecodeErr != nil {
+		pterm.Error.Printf("Error decoding flashcard JSON from '%s': %v\n", file.Path, file.DecodeErr)
+		if file.CorruptCopyErr != nil {
+			pterm.Warning.Printf("Could not keep a copy of the damaged file as '%s': %v\n", file.CorruptCopy, file.CorruptCopyErr)
+		} else if file.CorruptCopy != "" {
+			pterm.Info.Printf("Kept a copy of the damaged file as '%s'.\n", file.CorruptCopy)
+		}
+		if !file.Recovered {
+			pterm.Warning.Println("Could not load existing cards. Starting with an empty set.")
+			return
+		}
+		for _, skipped := range file.Skipped {
+			kind := "card"
+			if skipped.Trashed {
+				kind = "trashed card"
+			}
+			pterm.Warning.Printf("Skipped %s %d of '%s': %v\n", kind, skipped.Position, file.Path, skipped.Err)
 		}
+		pterm.Warning.Printf("Recovered %d of %d cards from '%s'.\n", file.Cards, file.Total, file.Path)
 	}
-	pterm.Info.Printf("Loaded %d flashcards from '%s'.\n", len(app.Flashcards), app.FilePath)
-	return nil
+
+	switch {
+	case file.Newer:
+		pterm.Warning.Printf("'%s' was written by a newer version of the app (format %d, this one knows %d). It is opened read-only so no data is lost.\n", file.Path, file.Version, deck.SchemaVersion)
+	case file.Upgraded:
+		pterm.Info.Printf("Upgraded '%s' from format %d to %d.\n", file.Path, file.Version, deck.SchemaVersion)
+	}
+	if file.Renumbered > 0 {
+		pterm.Warning.Printf("Found %d cards with duplicate IDs in '%s' and gave them new IDs.\n", file.Renumbered, file.Path)
+	}
+	app.afterSave(file.Saved)
+	if file.SaveErr != nil {
+		pterm.Error.Printf("Not saved: %v.\n", file.SaveErr)
+		pterm.Warning.Println("The changes could not be saved yet; they will be written with the next save.")
+	}
+	pterm.Info.Printf("Loaded %d flashcards from '%s'.\n", file.Cards, file.Path)
 }
 
+// saveFlashcards saves the open deck(s), reporting a failure, and runs the
+// post-save hook for every file written.
 func (app *FlashcardApp) saveFlashcards() error {
-	data, err := json.MarshalIndent(app.Flashcards, "", "  ")
+	saved, err := app.Save()
+	app.afterSave(saved)
 	if err != nil {
-		pterm.Error.Printf("Error encoding flashcards to JSON: %v\n", err)
-		return err
+		pterm.Error.Printf("Not saved: %v.\n", err)
 	}
+	return err
+}
 
-	err = ioutil.WriteFile(app.FilePath, data, 0644)
-	if err != nil {
-		pterm.Error.Printf("Error writing flashcard file '%s': %v\n", app.FilePath, err)
-		return err
+// afterSave runs the post-save hook for each saved file, or in a dry run
+// says what would have been written.
+func (app *FlashcardApp) afterSave(saved []deck.SavedFile) {
+	for _, file := range saved {
+		if app.DryRun {
+			pterm.Info.Printf("Dry run: would write %d cards (%d in the trash) to '%s'.\n", file.Cards, file.Trash, file.Path)
+			continue
+		}
+		app.runPostSaveHook(file.Path)
 	}
-	return nil
 }
 
-func (app *FlashcardApp) getNextID() int {
-	app.maxID++
-	return app.maxID
-}
+// runPostSaveHook runs the configured post-save command through the shell,
+// so it may quote arguments and use pipes, with the saved deck path as its
+// last argument. Failures are only reported; the save itself has already
+// succeeded.
+func (app *FlashcardApp) runPostSaveHook(path string) {
+	if strings.TrimSpace(app.PostSaveHook) == "" {
+		return
+	}
 
-func (app *FlashcardApp) addCard(question, answer, category string, options, correctAnswers []string) {
-	if category == "" {
-		category = "General"
+	timeout := app.PostSaveHookTimeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
 	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
 
-	if len(options) > 0 && len(correctAnswers) == 0 {
-		if len(options) > 0 {
-			correctAnswers = []string{options[0]}
-			pterm.Warning.Printf("No correct answer specified for multiple choice. Defaulting to first option: '%s'\n", options[0])
-		} else {
-			correctAnswers = []string{answer}
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", app.PostSaveHook+` "`+path+`"`)
+	} else {
+		// The path is passed as $1 so the shell never interprets it.
+		cmd = exec.CommandContext(ctx, "sh", "-c", app.PostSaveHook+` "$1"`, "sh", path)
+	}
+	output, err := cmd.CombinedOutput()
+	if ctx.Err() == context.DeadlineExceeded {
+		pterm.Warning.Printf("Post-save hook timed out after %s.\n", timeout)
+		return
+	}
+	if err != nil {
+		pterm.Warning.Printf("Post-save hook failed: %v\n", err)
+		if len(output) > 0 {
+			pterm.Warning.Println(strings.TrimSpace(string(output)))
 		}
-	} else if len(options) == 0 {
-
-		correctAnswers = []string{answer}
 	}
+}
 
-	newCard := Flashcard{
-		ID:             app.getNextID(),
-		Question:       question,
-		Answer:         answer,
-		CorrectAnswers: correctAnswers,
-		Options:        options,
-		Category:       category,
-		CreatedAt:      time.Now(),
-		LastReviewed:   nil,
-		TimesReviewed:  0,
-		TimesCorrect:   0,
+// acquireLock locks every open deck, so a second instance on the same deck
+// can't overwrite its changes.
+func (app *FlashcardApp) acquireLock() error {
+	stale, err := app.Lock()
+	for _, lock := range stale {
+		pterm.Info.Printf("Took over the lock of '%s' left by process %d, which is gone.\n", lock.Path, lock.PID)
 	}
+	return err
+}
 
-	app.Flashcards = append(app.Flashcards, newCard)
-	err := app.saveFlashcards()
-	if err == nil {
-		pterm.Success.Printf("Added new card (ID: %d) to '%s': %s\n", newCard.ID, app.FilePath, question)
+// releaseLock removes the lock files taken by acquireLock.
+func (app *FlashcardApp) releaseLock() {
+	if err := app.Unlock(); err != nil {
+		pterm.Warning.Printf("Could not remove lock file: %v\n", err)
 	}
 }
 
-func (app *FlashcardApp) findCardIndexByID(id int) (int, bool) {
-	for i, card := range app.Flashcards {
-		if card.ID == id {
-			return i, true
+// findDuplicates returns groups of cards sharing the same normalized
+// question, ordered by the lowest ID in each group.
+func (app *FlashcardApp) findDuplicates() [][]Flashcard {
+	groups := map[string][]Flashcard{}
+	order := []string{}
+	for _, card := range app.Flashcards {
+		key := deck.NormalizeQuestion(card.Question)
+		if _, seen := groups[key]; !seen {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], card)
+	}
+
+	duplicates := [][]Flashcard{}
+	for _, key := range order {
+		if len(groups[key]) > 1 {
+			sortCards(groups[key], "id")
+			duplicates = append(duplicates, groups[key])
 		}
 	}
-	return -1, false
+	sort.SliceStable(duplicates, func(i, j int) bool {
+		return duplicates[i][0].ID < duplicates[j][0].ID
+	})
+	return duplicates
 }
 
-func (app *FlashcardApp) reviewCards(categoryFilter string) {
-	reviewCards := []Flashcard{}
-	if categoryFilter != "" {
-		for _, card := range app.Flashcards {
-			if strings.EqualFold(card.Category, categoryFilter) {
-				reviewCards = append(reviewCards, card)
+// deckIssue is a problem validateDeck found with one card. Missing lists
+// the correct answers absent from a multiple-choice card's options.
+type deckIssue struct {
+	CardID  int
+	Problem string
+	Missing []string
+}
+
+// validateDeck checks every card for problems that make it unusable:
+// an empty question, no answer at all, multiple-choice cards with fewer
+// than two options and correct answers that are not among the options.
+func (app *FlashcardApp) validateDeck() []deckIssue {
+	issues := []deckIssue{}
+	for _, card := range app.Flashcards {
+		if strings.TrimSpace(card.Question) == "" {
+			issues = append(issues, deckIssue{CardID: card.ID, Problem: "empty question"})
+		}
+		if strings.TrimSpace(card.Answer) == "" && len(card.CorrectAnswers) == 0 {
+			issues = append(issues, deckIssue{CardID: card.ID, Problem: "no answer"})
+		}
+		if len(card.Options) == 0 {
+			continue
+		}
+		if len(card.Options) < 2 {
+			issues = append(issues, deckIssue{CardID: card.ID, Problem: fmt.Sprintf("only %d option", len(card.Options))})
+		}
+		missing := []string{}
+		for _, answer := range card.CorrectAnswers {
+			if strings.TrimSpace(answer) != "" && !deck.ContainsFold(card.Options, answer) {
+				missing = append(missing, answer)
 			}
 		}
-		pterm.Info.Printf("Reviewing %d cards in category '%s' from '%s'.\n", len(reviewCards), categoryFilter, app.FilePath)
-	} else {
-		reviewCards = app.Flashcards
-		pterm.Info.Printf("Reviewing all %d cards from '%s'.\n", len(reviewCards), app.FilePath)
+		if len(missing) > 0 {
+			issues = append(issues, deckIssue{
+				CardID:  card.ID,
+				Problem: fmt.Sprintf("correct answer not in options: %s", strings.Join(missing, ", ")),
+				Missing: missing,
+			})
+		}
 	}
+	return issues
+}
 
-	if len(reviewCards) == 0 {
-		pterm.Warning.Println("No cards to review in this selection.")
-		return
+// fixMissingOptions adds the correct answers reported missing by
+// validateDeck to their cards' options and returns how many cards changed.
+func (app *FlashcardApp) fixMissingOptions(issues []deckIssue) int {
+	fixed := 0
+	for _, issue := range issues {
+		if len(issue.Missing) == 0 {
+			continue
+		}
+		if index, found := app.CardIndex(issue.CardID); found {
+			app.Flashcards[index].Options = append(app.Flashcards[index].Options, issue.Missing...)
+			fixed++
+		}
 	}
+	return fixed
+}
 
-	rand.Shuffle(len(reviewCards), func(i, j int) {
-		reviewCards[i], reviewCards[j] = reviewCards[j], reviewCards[i]
-	})
+// newCard builds a card with deck.NewCard, warning when a multiple-choice
+// card defaults to its first option as the correct answer.
+func (app *FlashcardApp) newCard(question, answer, category string, options, correctAnswers []string) Flashcard {
+	if len(options) > 0 && len(correctAnswers) == 0 {
+		pterm.Warning.Printf("No correct answer specified for multiple choice. Defaulting to first option: '%s'\n", options[0])
+	}
+	return app.NewCard(question, answer, category, options, correctAnswers)
+}
 
-	correctCount := 0
-	totalCount := len(reviewCards)
+// addCard builds a card from the given fields and adds it with insertCard.
+func (app *FlashcardApp) addCard(question, answer, category string, options, correctAnswers []string) (Flashcard, error) {
+	return app.insertCard(app.newCard(question, answer, category, options, correctAnswers), false)
+}
 
-	for i, card := range reviewCards {
-		pterm.DefaultSection.Printf("Card %d/%d - Category: %s", i+1, totalCount, card.Category)
-		pterm.FgLightBlue.Println("Question: ", card.Question)
+// insertCard adds card to the deck with deck.Add and saves it, without
+// printing anything but save errors.
+func (app *FlashcardApp) insertCard(card Flashcard, allowDuplicate bool) (Flashcard, error) {
+	if err := app.Add(card, allowDuplicate); err != nil {
+		return card, err
+	}
+	return card, app.saveFlashcards()
+}
 
-		isMultipleChoice := len(card.Options) > 0
+// quickAddSyntax describes the line format of addCardFromLine.
+const quickAddSyntax = "Category :: Question :: Answer1 | Answer2"
 
-		if isMultipleChoice {
-			pterm.FgYellow.Println("\n(Multiple Choice Question)")
-			_, _ = pterm.DefaultInteractiveContinue.Show("Press Enter to see answer options...")
+// addCardFromLine adds a text card written on one line as
+// "Category :: Question :: Answer1 | Answer2". The category may be left out
+// ("Question :: Answer"), in which case it is General.
+func (app *FlashcardApp) addCardFromLine(line string) error {
+	parts := strings.Split(line, "::")
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+	}
+	var category, question, answerList string
+	switch len(parts) {
+	case 2:
+		question, answerList = parts[0], parts[1]
+	case 3:
+		category, question, answerList = parts[0], parts[1], parts[2]
+	default:
+		return fmt.Errorf("expected '%s' or 'Question :: Answer'", quickAddSyntax)
+	}
+	answers := splitList(answerList)
+	if question == "" {
+		return errors.New("the question is missing")
+	}
+	if len(answers) == 0 {
+		return errors.New("at least one answer is needed")
+	}
 
-			displayOptions := make([]string, len(card.Options))
-			copy(displayOptions, card.Options)
-			rand.Shuffle(len(displayOptions), func(k, l int) {
-				displayOptions[k], displayOptions[l] = displayOptions[l], displayOptions[k]
-			})
+	card := app.newCard(question, answers[0], category, nil, nil)
+	card.CorrectAnswers = answers
+	if !app.appendCard(card) {
+		return errors.New("card not added")
+	}
+	return nil
+}
 
-			for j, option := range displayOptions {
-				pterm.FgCyan.Printf("%d. %s\n", j+1, option)
-			}
-			_, _ = pterm.DefaultInteractiveContinue.Show("Press Enter to see the correct answer(s)...")
-		} else {
-			_, _ = pterm.DefaultInteractiveContinue.Show("Press Enter to see the answer...")
+// quickAdd reads cards in the addCardFromLine format until an empty line is
+// entered.
+func (app *FlashcardApp) quickAdd() {
+	pterm.Info.Printf("Quick add: one card per line as '%s' (category optional). Leave the line empty to stop.\n", quickAddSyntax)
+	added := 0
+	for {
+		line, _ := pterm.DefaultInteractiveTextInput.Show("Card")
+		if strings.TrimSpace(line) == "" {
+			break
 		}
-
-		if len(card.CorrectAnswers) > 1 {
-			pterm.FgLightGreen.Println("\nCorrect answers:")
-			for _, ans := range card.CorrectAnswers {
-				pterm.FgGreen.Println("- ", ans)
-			}
-		} else if len(card.CorrectAnswers) == 1 {
-			pterm.FgLightGreen.Println("\nAnswer:", card.CorrectAnswers[0])
-		} else {
-			pterm.FgLightGreen.Println("\nAnswer:", card.Answer)
+		if err := app.addCardFromLine(line); err != nil {
+			pterm.Error.Printf("Could not add card: %v.\n", err)
+			continue
 		}
+		added++
+	}
+	pterm.Info.Printf("Finished quick add (%d cards added).\n", added)
+}
 
-		result, _ := pterm.DefaultInteractiveConfirm.
-			WithDefaultValue(true).
-			WithConfirmText("y").
-			WithRejectText("n").
-			Show("Did you get it right?")
-
-		originalIndex, found := app.findCardIndexByID(card.ID)
-		if found {
-			now := time.Now()
-			app.Flashcards[originalIndex].TimesReviewed++
-			app.Flashcards[originalIndex].LastReviewed = &now
-			if result {
-				correctCount++
-				app.Flashcards[originalIndex].TimesCorrect++
-				pterm.Success.Println("Marked as correct!")
-			} else {
-				pterm.Warning.Println("Marked as incorrect.")
-			}
-		} else {
-			pterm.Error.Printf("Could not find card with ID %d in main list to update stats.\n", card.ID)
+// appendCard is the interactive side of insertCard: if a card with the same
+// question exists, it asks before adding another one, and it reports the
+// result.
+func (app *FlashcardApp) appendCard(newCard Flashcard) bool {
+	card, err := app.insertCard(newCard, false)
+	var duplicate *deck.DuplicateError
+	if errors.As(err, &duplicate) {
+		pterm.Warning.Printf("Card %d already asks '%s'.\n", duplicate.Existing.ID, duplicate.Existing.Question)
+		addAnyway, _ := pterm.DefaultInteractiveConfirm.
+			WithDefaultValue(false).
+			WithConfirmText("y").WithRejectText("n").
+			Show("Add it anyway?")
+		if !addAnyway {
+			pterm.Info.Println("Card not added.")
+			return false
 		}
-		fmt.Println()
+		card, err = app.insertCard(newCard, true)
 	}
-
-	err := app.saveFlashcards()
 	if err != nil {
-		pterm.Error.Println("Failed to save review results.")
-	}
-
-	score := 0.0
-	if totalCount > 0 {
-		score = (float64(correctCount) / float64(totalCount)) * 100
+		return false
 	}
-	pterm.Info.Printf("Review complete! You got %d/%d correct (%.1f%%).\n", correctCount, totalCount, score)
+	pterm.Success.Printf("Added new card (ID: %d) to '%s': %s\n", card.ID, app.FilePath, card.Question)
+	return true
 }
 
-func (app *FlashcardApp) quizMode(categoryFilter string, numQuestions int) {
-	quizCardsSource := []Flashcard{}
-	if categoryFilter != "" {
-		for _, card := range app.Flashcards {
-			if strings.EqualFold(card.Category, categoryFilter) {
-				quizCardsSource = append(quizCardsSource, card)
-			}
+// parseQuizlet splits a Quizlet export into term/definition pairs. Rows are
+// split on rowSep first, then each row on the first termSep, so definitions
+// may contain the term separator. Rows without a separator are reported by
+// their 1-based row number.
+func parseQuizlet(data, termSep, rowSep string) (pairs [][2]string, skipped []int) {
+	if rowSep == "\n" {
+		data = strings.ReplaceAll(data, "\r\n", "\n")
+	}
+	for i, row := range strings.Split(data, rowSep) {
+		if strings.TrimSpace(row) == "" {
+			continue
 		}
-		pterm.Info.Printf("Starting quiz with cards from category '%s' in '%s'.\n", categoryFilter, app.FilePath)
-	} else {
-		quizCardsSource = app.Flashcards
-		pterm.Info.Printf("Starting quiz with cards from all categories in '%s'.\n", app.FilePath)
+		parts := strings.SplitN(row, termSep, 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			skipped = append(skipped, i+1)
+			continue
+		}
+		pairs = append(pairs, [2]string{strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])})
 	}
+	return pairs, skipped
+}
 
-	if len(quizCardsSource) == 0 {
-		pterm.Warning.Println("No cards available for the quiz in this selection.")
-		return
-	}
-	if numQuestions > len(quizCardsSource) {
-		numQuestions = len(quizCardsSource)
-		pterm.Info.Printf("Reduced quiz size to %d questions (maximum available).\n", numQuestions)
-	}
-	if numQuestions <= 0 {
-		pterm.Warning.Println("Number of questions must be positive.")
-		return
+// Import modes decide what happens to an imported card whose question is
+// already in the deck (compared with deck.NormalizeQuestion).
+const (
+	importAppend    = "append"     // add it anyway
+	importSkipDupes = "skip-dupes" // leave it out
+	importReplace   = "replace"    // update the existing card's answers and options
+)
+
+var importModes = []string{importAppend, importSkipDupes, importReplace}
+
+// checkImportMode rejects unknown -import-mode values.
+func checkImportMode(mode string) error {
+	if slices.Contains(importModes, mode) {
+		return nil
 	}
+	return fmt.Errorf("unknown import mode '%s' (use %s)", mode, strings.Join(importModes, ", "))
+}
 
-	rand.Shuffle(len(quizCardsSource), func(i, j int) {
-		quizCardsSource[i], quizCardsSource[j] = quizCardsSource[j], quizCardsSource[i]
-	})
-	quizCards := quizCardsSource[:numQuestions]
+// importGuard applies ImportMode to the cards of one import. Cards added
+// earlier in the same import count as existing too, so a file repeating a
+// question is handled the same way.
+type importGuard struct {
+	app        *FlashcardApp
+	questions  map[string]int
+	Duplicates int
+	Replaced   int
+}
 
-	correctCount := 0
+func (app *FlashcardApp) newImportGuard() *importGuard {
+	guard := &importGuard{app: app, questions: make(map[string]int, len(app.Flashcards))}
+	for i, card := range app.Flashcards {
+		if _, seen := guard.questions[deck.NormalizeQuestion(card.Question)]; !seen {
+			guard.questions[deck.NormalizeQuestion(card.Question)] = i
+		}
+	}
+	return guard
+}
 
-	pterm.DefaultHeader.Printf("QUIZ MODE: %d questions from %s", numQuestions, app.FilePath)
+// add appends card to the deck unless its question already exists, in
+// which case it is skipped or, in replace mode, its answers and options are
+// copied onto the existing card, keeping that card's ID and stats. It
+// reports whether a new card was added.
+func (g *importGuard) add(card Flashcard) bool {
+	key := deck.NormalizeQuestion(card.Question)
+	if index, exists := g.questions[key]; exists && g.app.ImportMode != importAppend {
+		existing := &g.app.Flashcards[index]
+		if g.app.ImportMode == importReplace {
+			existing.Answer = card.Answer
+			existing.CorrectAnswers = card.CorrectAnswers
+			existing.Options = card.Options
+			existing.PrimaryAnswer = card.PrimaryAnswer
+			g.Replaced++
+			return false
+		}
+		g.Duplicates++
+		return false
+	}
+	g.app.Flashcards = append(g.app.Flashcards, card)
+	if _, exists := g.questions[key]; !exists {
+		g.questions[key] = len(g.app.Flashcards) - 1
+	}
+	return true
+}
 
-	for i, card := range quizCards {
-		pterm.DefaultSection.Printf("Question %d/%d", i+1, numQuestions)
-		pterm.FgLightBlue.Println(card.Question)
+// changed reports whether the import has to be saved besides added cards.
+func (g *importGuard) changed() bool {
+	return g.Replaced > 0
+}
 
-		isMultipleChoice := len(card.Options) > 0
-		isCorrect := false
-		var userAnswer string
+// report prints how many duplicates were skipped or updated.
+func (g *importGuard) report(path string) {
+	if g.Duplicates > 0 {
+		pterm.Info.Printf("Skipped %d cards from '%s' whose question is already in the deck (use -import-mode append to add them anyway).\n", g.Duplicates, path)
+	}
+	if g.Replaced > 0 {
+		pterm.Info.Printf("Updated the answers of %d existing cards from '%s'.\n", g.Replaced, path)
+	}
+}
 
-		if isMultipleChoice {
-			displayOptions := make([]string, len(card.Options))
-			copy(displayOptions, card.Options)
-			rand.Shuffle(len(displayOptions), func(k, l int) {
-				displayOptions[k], displayOptions[l] = displayOptions[l], displayOptions[k]
-			})
+// importQuizlet adds the cards of a Quizlet export (term -> question,
+// definition -> answer) to the given category and saves once at the end.
+func (app *FlashcardApp) importQuizlet(path, termSep, rowSep, category string) (int, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
 
-			optionChoices := []string{}
-			for j, option := range displayOptions {
-				optionChoices = append(optionChoices, fmt.Sprintf("%d. %s", j+1, option))
-			}
+	pairs, skipped := parseQuizlet(string(data), termSep, rowSep)
+	for _, row := range skipped {
+		pterm.Warning.Printf("Skipping row %d in '%s': no term separator found.\n", row, path)
+	}
+	if len(pairs) == 0 {
+		return 0, nil
+	}
 
-			selectedOptionStr, _ := pterm.DefaultInteractiveSelect.
-				WithOptions(optionChoices).
-				WithDefaultText("Select your answer").
-				Show()
+	guard := app.newImportGuard()
+	imported := 0
+	for _, pair := range pairs {
+		if guard.add(app.newCard(pair[0], pair[1], category, nil, nil)) {
+			imported++
+		}
+	}
+	guard.report(path)
+	if imported > 0 || guard.changed() {
+		if err := app.saveFlashcards(); err != nil {
+			return 0, err
+		}
+	}
+	return imported, nil
+}
 
-			parts := strings.SplitN(selectedOptionStr, ". ", 2)
-			if len(parts) == 2 {
-				userAnswer = parts[1]
-			} else {
-				userAnswer = selectedOptionStr
-			}
+// htmlTag matches HTML tags in Anki fields; htmlBreak matches the tags that
+// separate lines of text.
+var (
+	htmlTag   = regexp.MustCompile(`<[^>]*>`)
+	htmlBreak = regexp.MustCompile(`(?i)<br\s*/?>|</div>|</p>`)
+)
 
-			for _, correctAnswer := range card.CorrectAnswers {
-				if strings.EqualFold(userAnswer, correctAnswer) {
-					isCorrect = true
-					break
-				}
-			}
+// stripHTML turns an Anki field into plain text.
+func stripHTML(field string) string {
+	field = htmlBreak.ReplaceAllString(field, " ")
+	field = htmlTag.ReplaceAllString(field, "")
+	return strings.Join(strings.Fields(html.UnescapeString(field)), " ")
+}
 
-		} else {
-			userAnswer, _ = pterm.DefaultInteractiveTextInput.Show("Your answer")
-			userAnswer = strings.TrimSpace(userAnswer)
+// importAnki adds the cards of an Anki "notes in plain text" export
+// (front<TAB>back per line, extra columns ignored) and saves once at the
+// end. Without a category the file name is used. Lines starting with '#'
+// are headers; other lines without both sides are skipped.
+func (app *FlashcardApp) importAnki(path, category string) (imported, skipped int, err error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	if category == "" {
+		category = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
 
-			for _, correctAnswer := range card.CorrectAnswers {
-				if strings.EqualFold(userAnswer, correctAnswer) {
-					isCorrect = true
-					break
-				}
-			}
+	guard := app.newImportGuard()
+	for i, line := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
 		}
-
-		originalIndex, found := app.findCardIndexByID(card.ID)
-		if found {
-			now := time.Now()
-			app.Flashcards[originalIndex].TimesReviewed++
-			app.Flashcards[originalIndex].LastReviewed = &now
+		fields := strings.Split(line, "\t")
+		if len(fields) < 2 || stripHTML(fields[0]) == "" || stripHTML(fields[1]) == "" {
+			pterm.Warning.Printf("Skipping line %d in '%s': expected front and back separated by a tab.\n", i+1, path)
+			skipped++
+			continue
 		}
+		if guard.add(app.newCard(stripHTML(fields[0]), stripHTML(fields[1]), category, nil, nil)) {
+			imported++
+		}
+	}
+	guard.report(path)
 
-		if isCorrect {
-			pterm.Success.Println("Correct! ✓")
-			correctCount++
-			if found {
-				app.Flashcards[originalIndex].TimesCorrect++
-			}
-		} else {
-			pterm.Error.Print("Incorrect. ")
-			if len(card.CorrectAnswers) > 1 {
-				pterm.FgRed.Printf("The correct answers were: %s\n", strings.Join(card.CorrectAnswers, ", "))
-			} else if len(card.CorrectAnswers) == 1 {
-				pterm.FgRed.Printf("The correct answer was: %s\n", card.CorrectAnswers[0])
-			} else {
-				pterm.FgRed.Printf("The correct answer was: %s\n", card.Answer)
-			}
+	if imported > 0 || guard.changed() {
+		if err := app.saveFlashcards(); err != nil {
+			return 0, skipped, err
 		}
-		time.Sleep(500 * time.Millisecond)
-		fmt.Println()
 	}
+	return imported, skipped, nil
+}
 
-	err := app.saveFlashcards()
+// textBlock is one card being parsed by importText.
+type textBlock struct {
+	line     int
+	question string
+	answers  []string
+	category string
+	err      string
+}
+
+// importText adds cards from a plain text file of blocks separated by
+// blank lines. Each block has a "Q:" line, one or more "A:" lines (all
+// become correct answers) and an optional "C:" line; the category defaults
+// to the given one. Malformed blocks are reported with their line number
+// and skipped.
+func (app *FlashcardApp) importText(path, category string) (imported, skipped int, err error) {
+	data, err := ioutil.ReadFile(path)
 	if err != nil {
-		pterm.Error.Println("Failed to save quiz results.")
+		return 0, 0, err
 	}
 
-	score := 0.0
-	if numQuestions > 0 {
-		score = (float64(correctCount) / float64(numQuestions)) * 100
+	blocks := []*textBlock{}
+	var current *textBlock
+	for i, line := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			current = nil
+			continue
+		}
+		if current == nil {
+			current = &textBlock{line: i + 1, category: category}
+			blocks = append(blocks, current)
+		}
+		if current.err != "" {
+			continue
+		}
+
+		prefix, value, _ := strings.Cut(line, ":")
+		value = strings.TrimSpace(value)
+		switch strings.ToUpper(prefix) {
+		case "Q":
+			if current.question != "" {
+				current.err = fmt.Sprintf("line %d: second Q: line (missing blank line between cards?)", i+1)
+			}
+			current.question = value
+		case "A":
+			if value != "" {
+				current.answers = append(current.answers, value)
+			}
+		case "C":
+			current.category = value
+		default:
+			current.err = fmt.Sprintf("line %d: expected a line starting with Q:, A: or C:", i+1)
+		}
+	}
+
+	guard := app.newImportGuard()
+	for _, block := range blocks {
+		switch {
+		case block.err != "":
+		case block.question == "":
+			block.err = "no Q: line"
+		case len(block.answers) == 0:
+			block.err = "no A: line"
+		}
+		if block.err != "" {
+			pterm.Warning.Printf("Skipping card at line %d in '%s': %s.\n", block.line, path, block.err)
+			skipped++
+			continue
+		}
+		card := app.newCard(block.question, block.answers[0], block.category, nil, nil)
+		card.CorrectAnswers = block.answers
+		if guard.add(card) {
+			imported++
+		}
+	}
+	guard.report(path)
+
+	if imported > 0 || guard.changed() {
+		if err := app.saveFlashcards(); err != nil {
+			return 0, skipped, err
+		}
 	}
-	pterm.Info.Printf("Quiz complete! You scored %d/%d (%.1f%%).\n", correctCount, numQuestions, score)
+	return imported, skipped, nil
 }
 
-func (app *FlashcardApp) listCards(categoryFilter string) {
-	displayCards := []Flashcard{}
+// csvHeader is the column layout used by exportCSV and importCSV. Options
+// and correct answers are separated by '|' so multiple-choice cards
+// round-trip.
+var csvHeader = []string{"question", "answer", "category", "options", "correct_answers", "note"}
+
+// exportCSV writes all cards, sorted by ID, to a CSV file.
+func (app *FlashcardApp) exportCSV(path string) (int, error) {
+	cards := append([]Flashcard{}, app.Flashcards...)
+	sortCards(cards, "id")
+
+	file, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	w := csv.NewWriter(file)
+	_ = w.Write(csvHeader)
+	for _, card := range cards {
+		_ = w.Write([]string{
+			card.Question,
+			card.Answer,
+			card.Category,
+			strings.Join(card.Options, "|"),
+			strings.Join(card.CorrectAnswers, "|"),
+			card.Note,
+		})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return 0, err
+	}
+	return len(cards), file.Close()
+}
+
+// importCSV adds the cards from a CSV file in the exportCSV layout under
+// fresh IDs. Rows without a question are skipped with a warning.
+func (app *FlashcardApp) importCSV(path string) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	r := csv.NewReader(file)
+	r.FieldsPerRecord = -1
+	rows, err := r.ReadAll()
+	if err != nil {
+		return 0, err
+	}
+
+	guard := app.newImportGuard()
+	imported := 0
+	for i, row := range rows {
+		if i == 0 && len(row) > 0 && strings.EqualFold(strings.TrimSpace(row[0]), csvHeader[0]) {
+			continue
+		}
+		for len(row) < len(csvHeader) {
+			row = append(row, "")
+		}
+		question := strings.TrimSpace(row[0])
+		if question == "" {
+			pterm.Warning.Printf("Skipping row %d in '%s': empty question.\n", i+1, path)
+			continue
+		}
+		card := app.newCard(
+			question,
+			strings.TrimSpace(row[1]),
+			strings.TrimSpace(row[2]),
+			splitList(row[3]),
+			splitList(row[4]),
+		)
+		card.Note = strings.TrimSpace(row[5])
+		if guard.add(card) {
+			imported++
+		}
+	}
+	guard.report(path)
+
+	if imported > 0 || guard.changed() {
+		if err := app.saveFlashcards(); err != nil {
+			return 0, err
+		}
+	}
+	return imported, nil
+}
+
+// exportJSONL writes all cards, sorted by ID, to path as JSON lines: one
+// compact card object per line.
+func (app *FlashcardApp) exportJSONL(path string) (int, error) {
+	cards := append([]Flashcard{}, app.Flashcards...)
+	sortCards(cards, "id")
+
+	file, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	w := bufio.NewWriter(file)
+	enc := json.NewEncoder(w)
+	for _, card := range cards {
+		if err := enc.Encode(card); err != nil {
+			return 0, err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return 0, err
+	}
+	return len(cards), file.Close()
+}
+
+// importJSONL adds the cards of a JSON lines file, one card object per
+// line, under fresh IDs. Stats in the file are kept. Blank lines are
+// ignored; malformed lines and cards without a question are skipped with a
+// warning.
+func (app *FlashcardApp) importJSONL(path string) (imported, skipped int, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer file.Close()
+
+	guard := app.newImportGuard()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var card Flashcard
+		if err := json.Unmarshal([]byte(text), &card); err != nil {
+			pterm.Warning.Printf("Skipping line %d in '%s': %v.\n", line, path, err)
+			skipped++
+			continue
+		}
+		if strings.TrimSpace(card.Question) == "" {
+			pterm.Warning.Printf("Skipping line %d in '%s': empty question.\n", line, path)
+			skipped++
+			continue
+		}
+		card.ID = app.NextID()
+		card.DeletedAt = nil
+		if card.EaseFactor == 0 {
+			card.EaseFactor = 2.5
+		}
+		if card.Difficulty = strings.ToLower(strings.TrimSpace(card.Difficulty)); !deck.IsDifficulty(card.Difficulty) {
+			card.Difficulty = deck.DifficultyMedium
+		}
+		if card.CreatedAt.IsZero() {
+			card.CreatedAt = time.Now()
+		}
+		if guard.add(card) {
+			imported++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, skipped, err
+	}
+	guard.report(path)
+
+	if imported > 0 || guard.changed() {
+		if err := app.saveFlashcards(); err != nil {
+			return 0, skipped, err
+		}
+	}
+	return imported, skipped, nil
+}
+
+// exportMarkdown writes a printable study sheet: a table of card counts per
+// category, then every card as a "### Question" heading with its answers
+// (and options, for multiple choice) as bullet lists, grouped by category.
+func (app *FlashcardApp) exportMarkdown(path, categoryFilter string) (int, error) {
+	cards := deck.FilterByCategory(app.Flashcards, categoryFilter)
+	sort.SliceStable(cards, func(i, j int) bool {
+		if cards[i].Category != cards[j].Category {
+			return cards[i].Category < cards[j].Category
+		}
+		return cards[i].ID < cards[j].ID
+	})
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Flashcards: %s\n\n", filepath.Base(app.FilePath))
+	b.WriteString("| Category | Cards |\n|---|---|\n")
+	counts := map[string]int{}
+	categories := []string{}
+	for _, card := range cards {
+		if counts[card.Category] == 0 {
+			categories = append(categories, card.Category)
+		}
+		counts[card.Category]++
+	}
+	for _, category := range categories {
+		fmt.Fprintf(&b, "| %s | %d |\n", category, counts[category])
+	}
+
+	current := ""
+	for i, card := range cards {
+		if i == 0 || card.Category != current {
+			current = card.Category
+			fmt.Fprintf(&b, "\n## %s\n", current)
+		}
+		fmt.Fprintf(&b, "\n### %s\n\n", card.Question)
+		if len(card.Options) > 0 {
+			b.WriteString("Options:\n\n")
+			for _, option := range card.Options {
+				fmt.Fprintf(&b, "- %s\n", option)
+			}
+			b.WriteString("\nAnswer:\n\n")
+		}
+		answers := card.CorrectAnswers
+		if len(answers) == 0 {
+			answers = []string{card.Answer}
+		}
+		for _, answer := range answers {
+			fmt.Fprintf(&b, "- %s\n", answer)
+		}
+		if card.Note != "" {
+			fmt.Fprintf(&b, "\n> Note: %s\n", card.Note)
+		}
+	}
+
+	if err := ioutil.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		return 0, err
+	}
+	return len(cards), nil
+}
+
+// exportCategory writes the cards of one category (ignoring case) to a new
+// deck file at destPath, numbered from 1. An existing file is only replaced
+// with Force set. Nothing is written when no card matches; the returned
+// count is then 0.
+func (app *FlashcardApp) exportCategory(category, destPath string) (int, error) {
+	return app.writeNewDeck(deck.FilterByCategory(app.Flashcards, category), destPath, app.Force)
+}
+
+// writeNewDeck writes cards, in ID order and renumbered from 1, to a new
+// deck file at destPath, refusing to replace an existing file unless
+// replace is set. Nothing is written for no cards; the returned count is
+// then 0.
+func (app *FlashcardApp) writeNewDeck(cards []Flashcard, destPath string, replace bool) (int, error) {
+	if app.isOpenDeck(destPath) {
+		return 0, errors.New("the destination is the current deck")
+	}
+	if len(cards) == 0 {
+		return 0, nil
+	}
+	if _, err := os.Stat(destPath); err == nil && !replace {
+		return 0, errors.New("the file already exists (use -force to replace it)")
+	}
+	cards = append([]Flashcard(nil), cards...)
+	sort.SliceStable(cards, func(i, j int) bool { return cards[i].ID < cards[j].ID })
+	for i := range cards {
+		cards[i].ID = i + 1
+	}
+
+	if _, err := (&deck.Deck{FilePath: destPath, Flashcards: cards}).Save(); err != nil {
+		return 0, err
+	}
+	return len(cards), nil
+}
+
+// splitList splits a '|'-separated cell into its trimmed, non-empty items.
+func splitList(cell string) []string {
+	var items []string
+	for _, item := range strings.Split(cell, "|") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// cardProgress is the personal learning state of a card, kept apart from
+// its content so progress can be backed up or moved between copies of a deck.
+type cardProgress struct {
+	ID             int        `json:"id"`
+	LastReviewed   *time.Time `json:"last_reviewed,omitempty"`
+	LastCorrect    *bool      `json:"last_correct,omitempty"`
+	TimesReviewed  int        `json:"times_reviewed"`
+	TimesCorrect   int        `json:"times_correct"`
+	TotalTimeMs    int64      `json:"total_time_ms"`
+	TimesAnswered  int        `json:"times_answered,omitempty"`
+	EaseFactor     float64    `json:"ease_factor,omitempty"`
+	Interval       int        `json:"interval,omitempty"`
+	Repetitions    int        `json:"repetitions,omitempty"`
+	Box            int        `json:"box,omitempty"`
+	Stability      float64    `json:"stability,omitempty"`
+	FSRSDifficulty float64    `json:"fsrs_difficulty,omitempty"`
+	NextReview     *time.Time `json:"next_review,omitempty"`
+}
+
+func progressOf(card Flashcard) cardProgress {
+	return cardProgress{
+		ID:             card.ID,
+		LastReviewed:   card.LastReviewed,
+		LastCorrect:    card.LastCorrect,
+		TimesReviewed:  card.TimesReviewed,
+		TimesCorrect:   card.TimesCorrect,
+		TotalTimeMs:    card.TotalTimeMs,
+		TimesAnswered:  card.TimesAnswered,
+		EaseFactor:     card.EaseFactor,
+		Interval:       card.Interval,
+		Repetitions:    card.Repetitions,
+		Box:            card.Box,
+		Stability:      card.Stability,
+		FSRSDifficulty: card.FSRSDifficulty,
+		NextReview:     card.NextReview,
+	}
+}
+
+// applyTo overwrites the card's statistics and scheduling state with p.
+func (p cardProgress) applyTo(card *Flashcard) {
+	card.LastReviewed = p.LastReviewed
+	card.LastCorrect = p.LastCorrect
+	card.TimesReviewed = p.TimesReviewed
+	card.TimesCorrect = p.TimesCorrect
+	card.TotalTimeMs = p.TotalTimeMs
+	card.TimesAnswered = p.TimesAnswered
+	card.EaseFactor = p.EaseFactor
+	card.Interval = p.Interval
+	card.Repetitions = p.Repetitions
+	card.Box = p.Box
+	card.Stability = p.Stability
+	card.FSRSDifficulty = p.FSRSDifficulty
+	card.NextReview = p.NextReview
+}
+
+// exportProgress writes the statistics and scheduling state of every card,
+// keyed by card ID, to path.
+func (app *FlashcardApp) exportProgress(path string) (int, error) {
+	progress := make([]cardProgress, 0, len(app.Flashcards))
+	for _, card := range app.Flashcards {
+		progress = append(progress, progressOf(card))
+	}
+	sort.Slice(progress, func(i, j int) bool {
+		return progress[i].ID < progress[j].ID
+	})
+
+	data, err := json.MarshalIndent(progress, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		return 0, err
+	}
+	return len(progress), nil
+}
+
+// importProgress merges progress exported by exportProgress back onto the
+// cards with matching IDs. Entries for IDs not in the deck are skipped.
+func (app *FlashcardApp) importProgress(path string) (int, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	var progress []cardProgress
+	if err := json.Unmarshal(data, &progress); err != nil {
+		return 0, err
+	}
+
+	merged := 0
+	for _, p := range progress {
+		index, found := app.CardIndex(p.ID)
+		if !found {
+			pterm.Warning.Printf("Skipping progress for card ID %d: not in '%s'.\n", p.ID, app.FilePath)
+			continue
+		}
+		p.applyTo(&app.Flashcards[index])
+		merged++
+	}
+
+	if merged > 0 {
+		if err := app.saveFlashcards(); err != nil {
+			return 0, err
+		}
+	}
+	return merged, nil
+}
+
+// resetStats clears the review statistics and scheduling state of the cards
+// in the category (all cards for ""), so they are learned from scratch, and
+// returns how many cards were reset.
+func (app *FlashcardApp) resetStats(categoryFilter string) (int, error) {
+	reset := 0
+	for i := range app.Flashcards {
+		if categoryFilter != "" && !strings.EqualFold(app.Flashcards[i].Category, categoryFilter) {
+			continue
+		}
+		cardProgress{ID: app.Flashcards[i].ID, EaseFactor: 2.5}.applyTo(&app.Flashcards[i])
+		reset++
+	}
+	if reset == 0 {
+		return 0, nil
+	}
+	if err := app.saveFlashcards(); err != nil {
+		return 0, err
+	}
+	return reset, nil
+}
+
+func (app *FlashcardApp) undoPath() string {
+	return app.FilePath + ".undo"
+}
+
+// snapshotSession remembers the deck as it is before a review or quiz
+// session, in memory and in the .undo file, so undoLastSession can roll the
+// session back even after a restart.
+func (app *FlashcardApp) snapshotSession() {
+	app.sessionMu.Lock()
+	app.inSession = true
+	app.sessionMu.Unlock()
+	app.lastSnapshot = append([]Flashcard(nil), app.Flashcards...)
+	if app.DryRun {
+		return
+	}
+	data, err := json.MarshalIndent(app.lastSnapshot, "", "  ")
+	if err == nil {
+		err = ioutil.WriteFile(app.undoPath(), data, 0644)
+	}
+	if err != nil {
+		pterm.Warning.Printf("Could not write undo file '%s': %v\n", app.undoPath(), err)
+	}
+}
+
+// undoLastSession restores the statistics and scheduling state every card
+// had before the last review or quiz session. Card content, and cards added
+// since, are left alone.
+func (app *FlashcardApp) undoLastSession() bool {
+	snapshot := app.lastSnapshot
+	if snapshot == nil {
+		data, err := ioutil.ReadFile(app.undoPath())
+		if err == nil {
+			err = json.Unmarshal(data, &snapshot)
+		}
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			pterm.Error.Printf("Could not read undo file '%s': %v\n", app.undoPath(), err)
+			return false
+		}
+	}
+	if snapshot == nil {
+		pterm.Info.Println("No review or quiz session to undo yet.")
+		return false
+	}
+
+	for _, card := range snapshot {
+		if index, found := app.CardIndex(card.ID); found {
+			progressOf(card).applyTo(&app.Flashcards[index])
+		}
+	}
+	if err := app.saveFlashcards(); err != nil {
+		return false
+	}
+	app.lastSnapshot = nil
+	if app.DryRun {
+		pterm.Success.Println("Restored the statistics from before the last session.")
+		return true
+	}
+	if err := os.Remove(app.undoPath()); err != nil && !errors.Is(err, os.ErrNotExist) {
+		pterm.Warning.Printf("Could not remove undo file '%s': %v\n", app.undoPath(), err)
+	}
+	pterm.Success.Println("Restored the statistics from before the last session.")
+	return true
+}
+
+// unescapeSeparator turns escape sequences like \t and \n given on the
+// command line into the characters they stand for.
+func unescapeSeparator(sep string) string {
+	if unquoted, err := strconv.Unquote(`"` + sep + `"`); err == nil {
+		return unquoted
+	}
+	return sep
+}
+
+func (app *FlashcardApp) reviewCards(categoryFilter, difficultyFilter string) {
+	reviewCards := []Flashcard{}
 	if categoryFilter != "" {
 		for _, card := range app.Flashcards {
 			if strings.EqualFold(card.Category, categoryFilter) {
-				displayCards = append(displayCards, card)
+				reviewCards = append(reviewCards, card)
+			}
+		}
+		pterm.Info.Printf("Reviewing %d cards in category '%s' from '%s'.\n", len(reviewCards), categoryFilter, app.FilePath)
+	} else {
+		reviewCards = app.Flashcards
+		pterm.Info.Printf("Reviewing all %d cards from '%s'.\n", len(reviewCards), app.FilePath)
+	}
+	if app.leitnerOnly {
+		leitner := []Flashcard{}
+		for _, card := range reviewCards {
+			if app.schedulerFor(card) == schedulerLeitner {
+				leitner = append(leitner, card)
+			}
+		}
+		reviewCards = leitner
+		pterm.Info.Printf("%d of them are in a Leitner box.\n", len(reviewCards))
+	}
+	if difficultyFilter != "" {
+		reviewCards = deck.FilterByDifficulty(reviewCards, difficultyFilter)
+		pterm.Info.Printf("%d of them are marked %s.\n", len(reviewCards), difficultyFilter)
+	}
+	if app.TagFilter != "" {
+		reviewCards = deck.FilterByTag(reviewCards, app.TagFilter)
+		pterm.Info.Printf("%d of them are tagged '%s'.\n", len(reviewCards), app.TagFilter)
+	}
+	if app.MistakesOnly {
+		reviewCards = deck.OnlyMistakes(reviewCards)
+		pterm.Info.Printf("%d of them were answered wrong last time.\n", len(reviewCards))
+	}
+	selected := len(reviewCards)
+	if !app.MistakesOnly && !app.reviewAll {
+		reviewCards = app.onlyDue(reviewCards, time.Now())
+	}
+	if len(reviewCards) < selected {
+		pterm.Info.Printf("%d of them are due for review.\n", len(reviewCards))
+	}
+
+	if len(reviewCards) == 0 {
+		pterm.Warning.Println("No cards to review in this selection.")
+		return
+	}
+
+	if app.SessionLimit > 0 && len(reviewCards) > app.SessionLimit {
+		pterm.Info.Printf("Selected the %d least recently reviewed of %d cards.\n", app.SessionLimit, len(reviewCards))
+		reviewCards = leastRecentlyReviewed(reviewCards, app.SessionLimit)
+	}
+	if app.overdueFirst {
+		reviewCards = sortByOverdue(reviewCards)
+	} else {
+		reviewCards = app.sessionOrder(reviewCards)
+	}
+	reviewCards, held := app.applyDailyLimits(reviewCards)
+	app.printHeldBack(held)
+	if len(reviewCards) == 0 {
+		pterm.Warning.Println("Today's limits are reached. Come back tomorrow!")
+		return
+	}
+	app.snapshotSession()
+
+	correctCount := 0
+	totalCount := len(reviewCards)
+	missed := []Flashcard{}
+	results := []sessionResult{}
+
+	requeued := 0
+	for i := 0; i < len(reviewCards); i++ {
+		card := reviewCards[i]
+		printProgress(i+1, len(reviewCards))
+		if i >= totalCount {
+			// A re-queued card only gets a second look; its first attempt
+			// already went into the stats.
+			if app.showReviewCard(app.presentCard(card), fmt.Sprintf("Again - Card %d/%d - Category: %s", i+1, len(reviewCards), card.Category)) >= 3 {
+				pterm.Success.Println("Got it this time!")
+			} else {
+				pterm.Warning.Println("Still missed.")
+			}
+			fmt.Println()
+			continue
+		}
+		heading := fmt.Sprintf("Card %d/%d - Category: %s", i+1, len(reviewCards), card.Category)
+		if scheduler := app.schedulerFor(card); scheduler != schedulerNone {
+			heading += " - Scheduler: " + scheduler
+		}
+		shownAt := time.Now()
+		quality := app.showReviewCard(app.presentCard(card), heading)
+		result := quality >= 3
+		elapsed := time.Since(shownAt)
+
+		app.sessionMu.Lock()
+		originalIndex, found := app.CardIndex(card.ID)
+		if found {
+			now := time.Now()
+			app.Flashcards[originalIndex].TimesReviewed++
+			app.Flashcards[originalIndex].LastReviewed = &now
+			app.Flashcards[originalIndex].LastCorrect = &result
+			app.Flashcards[originalIndex].TotalTimeMs += elapsed.Milliseconds()
+			app.Flashcards[originalIndex].TimesAnswered++
+			if result {
+				correctCount++
+				app.Flashcards[originalIndex].TimesCorrect++
+				pterm.Success.Println("Marked as correct!")
+			} else {
+				pterm.Warning.Println("Marked as incorrect.")
+			}
+			app.scheduleCard(&app.Flashcards[originalIndex], quality, now)
+		} else {
+			pterm.Error.Printf("Could not find card with ID %d in main list to update stats.\n", card.ID)
+		}
+		app.sessionMu.Unlock()
+		results = append(results, sessionResult{Card: card, Correct: result})
+		if !result {
+			missed = append(missed, card)
+			if app.Requeue {
+				reviewCards = append(reviewCards, card)
+				requeued++
+				pterm.Info.Println("You will see this card again before the session ends.")
 			}
 		}
-	} else {
-		displayCards = app.Flashcards
+		fmt.Println()
+	}
+
+	app.sessionMu.Lock()
+	err := app.saveFlashcards()
+	app.inSession = false
+	app.sessionMu.Unlock()
+	if err != nil {
+		pterm.Error.Println("Failed to save review results.")
+	}
+	if requeued > 0 {
+		pterm.Info.Printf("Re-queued %d cards during the session.\n", requeued)
+	}
+	app.recordStudyDay()
+	app.recordDailyCounts(results)
+
+	score := 0.0
+	if totalCount > 0 {
+		score = (float64(correctCount) / float64(totalCount)) * 100
+	}
+	pterm.Info.Printf("Review complete! You got %d/%d correct (%.1f%%).\n", correctCount, totalCount, score)
+	app.recordSession("review", categoryFilter, totalCount, correctCount)
+	app.printSessionSummary(results)
+
+	if app.RepeatMissed && len(missed) > 0 {
+		app.repeatMissed(missed, correctCount, totalCount)
+	}
+}
+
+// sessionResult is the outcome of one card in a review or quiz session.
+type sessionResult struct {
+	Card    Flashcard
+	Correct bool
+}
+
+// printSessionSummary adds detail to the end-of-session score when Summary
+// is "full": a per-category breakdown if the session spanned several
+// categories, and the cards that were answered incorrectly.
+func (app *FlashcardApp) printSessionSummary(results []sessionResult) {
+	if app.Summary != "full" || len(results) == 0 {
+		return
+	}
+
+	byCategory := make(map[string]*deck.CategoryStat)
+	categories := []string{}
+	incorrect := []Flashcard{}
+	for _, result := range results {
+		stat, ok := byCategory[result.Card.Category]
+		if !ok {
+			stat = &deck.CategoryStat{Category: result.Card.Category}
+			byCategory[result.Card.Category] = stat
+			categories = append(categories, result.Card.Category)
+		}
+		stat.Cards++
+		stat.Reviewed++
+		if result.Correct {
+			stat.Correct++
+		} else {
+			incorrect = append(incorrect, result.Card)
+		}
+	}
+
+	if len(categories) > 1 {
+		sort.Strings(categories)
+		tableData := pterm.TableData{{"Category", "Correct", "Total", "Score"}}
+		for _, category := range categories {
+			stat := byCategory[category]
+			tableData = append(tableData, []string{
+				category,
+				strconv.Itoa(stat.Correct),
+				strconv.Itoa(stat.Reviewed),
+				fmt.Sprintf("%.1f%%", stat.Accuracy()),
+			})
+		}
+		pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+	}
+
+	if len(incorrect) > 0 {
+		sortCards(incorrect, "id")
+		pterm.Info.Println("Answered incorrectly:")
+		for _, card := range incorrect {
+			pterm.FgRed.Printf("- (ID %d) %s\n", card.ID, card.Question)
+		}
+	}
+}
+
+// isDue reports whether a card is due for review: never scheduled, or
+// scheduled for some time today or earlier, so a card rescheduled at 10:00
+// is already due the next morning. Cards without a scheduler are always due.
+func (app *FlashcardApp) isDue(card Flashcard, now time.Time) bool {
+	if app.schedulerFor(card) == schedulerNone {
+		return true
+	}
+	year, month, day := now.Date()
+	endOfDay := time.Date(year, month, day+1, 0, 0, 0, 0, now.Location())
+	return card.NextReview == nil || card.NextReview.Before(endOfDay)
+}
+
+func (app *FlashcardApp) onlyDue(cards []Flashcard, now time.Time) []Flashcard {
+	due := []Flashcard{}
+	for _, card := range cards {
+		if app.isDue(card, now) {
+			due = append(due, card)
+		}
+	}
+	return due
+}
+
+// sortByOverdue orders cards by how long they have been due, longest first.
+// Cards that were never scheduled follow in ID order.
+func sortByOverdue(cards []Flashcard) []Flashcard {
+	sorted := slices.Clone(cards)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i].NextReview, sorted[j].NextReview
+		switch {
+		case a == nil && b == nil:
+			return sorted[i].ID < sorted[j].ID
+		case a == nil || b == nil:
+			return b == nil
+		}
+		return a.Before(*b)
+	})
+	return sorted
+}
+
+// dueCount returns how many cards are due by the end of today (local
+// time). Cards without a scheduler are always due.
+func (app *FlashcardApp) dueCount() int {
+	return len(app.onlyDue(app.Flashcards, time.Now()))
+}
+
+// rng drives all shuffling. It is replaced with a fixed-seed source for
+// -seed; the global rand functions can't be seeded since Go 1.24.
+var rng = rand.New(rand.NewSource(time.Now().UnixNano()))
+
+// printProgress prints a bar for "card current of total" above the card.
+// It is a static line rather than a live pterm progress bar, which would
+// redraw over the interactive prompts. Without a terminal it prints a plain
+// percentage line.
+func printProgress(current, total int) {
+	if total <= 0 {
+		return
+	}
+	percent := current * 100 / total
+	if !isTerminal(os.Stdout) {
+		fmt.Printf("Progress: card %d of %d (%d%%)\n", current, total, percent)
+		return
+	}
+	const width = 30
+	filled := current * width / total
+	bar := pterm.FgCyan.Sprint(strings.Repeat("█", filled)) + pterm.FgGray.Sprint(strings.Repeat("█", width-filled))
+	pterm.Printf("%s %d/%d (%d%%)\n", bar, current, total, percent)
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// leastRecentlyReviewed returns the n cards reviewed longest ago, with
+// never-reviewed cards first.
+func leastRecentlyReviewed(cards []Flashcard, n int) []Flashcard {
+	sorted := append([]Flashcard(nil), cards...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i].LastReviewed, sorted[j].LastReviewed
+		switch {
+		case a == nil || b == nil:
+			return a == nil && b != nil
+		case !a.Equal(*b):
+			return a.Before(*b)
+		}
+		return sorted[i].ID < sorted[j].ID
+	})
+	if n < len(sorted) {
+		sorted = sorted[:n]
+	}
+	return sorted
+}
+
+// sessionOrder returns a copy of cards in the order a session presents
+// them: shuffled, or by ID when NoShuffle is set.
+func (app *FlashcardApp) sessionOrder(cards []Flashcard) []Flashcard {
+	ordered := append([]Flashcard(nil), cards...)
+	if app.NoShuffle {
+		sortCards(ordered, "id")
+		return ordered
+	}
+	rng.Shuffle(len(ordered), func(i, j int) {
+		ordered[i], ordered[j] = ordered[j], ordered[i]
+	})
+	return ordered
+}
+
+// selectWeighted draws n distinct cards at random, favouring cards with a
+// low share of correct answers. Never-reviewed cards weigh as much as cards
+// that were always missed; even perfect cards keep a small chance.
+func selectWeighted(cards []Flashcard, n int) []Flashcard {
+	pool := append([]Flashcard(nil), cards...)
+	weights := make([]float64, len(pool))
+	total := 0.0
+	for i, card := range pool {
+		weights[i] = 1.1
+		if card.TimesReviewed > 0 {
+			weights[i] -= float64(card.TimesCorrect) / float64(card.TimesReviewed)
+		}
+		total += weights[i]
+	}
+
+	selected := make([]Flashcard, 0, n)
+	for len(selected) < n && len(pool) > 0 {
+		pick := len(pool) - 1
+		target := rng.Float64() * total
+		for i, weight := range weights {
+			if target < weight {
+				pick = i
+				break
+			}
+			target -= weight
+		}
+		selected = append(selected, pool[pick])
+		total -= weights[pick]
+		pool = append(pool[:pick], pool[pick+1:]...)
+		weights = append(weights[:pick], weights[pick+1:]...)
+	}
+	return selected
+}
+
+// repeatMissed drills the cards missed in the main review pass until each
+// one has been answered correctly. Only the first pass counts towards the
+// stored statistics.
+func (app *FlashcardApp) repeatMissed(missed []Flashcard, firstPassCorrect, totalCount int) {
+	pterm.DefaultHeader.Printf("Repeating %d missed cards", len(missed))
+
+	recovered := []Flashcard{}
+	round := 1
+	for len(missed) > 0 {
+		missed = app.sessionOrder(missed)
+
+		stillMissed := []Flashcard{}
+		for i, card := range missed {
+			result := app.showReviewCard(app.presentCard(card), fmt.Sprintf("Repeat round %d - Card %d/%d - Category: %s", round, i+1, len(missed), card.Category)) >= 3
+			if result {
+				recovered = append(recovered, card)
+				pterm.Success.Println("Got it this time!")
+			} else {
+				stillMissed = append(stillMissed, card)
+				pterm.Warning.Println("Still missed, it will come back.")
+			}
+			fmt.Println()
+		}
+		missed = stillMissed
+		round++
+	}
+
+	pterm.Info.Println("Eventually got right:")
+	for _, card := range recovered {
+		pterm.FgGreen.Printf("- (ID %d) %s\n", card.ID, card.Question)
+	}
+
+	firstScore := (float64(firstPassCorrect) / float64(totalCount)) * 100
+	pterm.Info.Printf("First pass: %d/%d (%.1f%%). Final: %d/%d (100.0%%) after %d repeat round(s).\n",
+		firstPassCorrect, totalCount, firstScore, totalCount, totalCount, round-1)
+}
+
+// showReviewCard displays a card, reveals its answer and asks for a
+// self-grade. It returns the SM-2 quality 0-5; 3 and above means the card
+// was remembered.
+func (app *FlashcardApp) showReviewCard(card Flashcard, heading string) int {
+	pterm.DefaultSection.Println(heading)
+	pterm.FgLightBlue.Println("Question: ", card.Question)
+	printAttachment(card)
+
+	isMultipleChoice := len(card.Options) > 0
+
+	if isMultipleChoice {
+		if isSelectAll(card) {
+			pterm.FgYellow.Println("\n(Multiple Choice Question, select all that apply)")
+		} else {
+			pterm.FgYellow.Println("\n(Multiple Choice Question)")
+		}
+		app.waitForReveal(card, "Press Enter to see answer options...")
+
+		displayOptions := shuffledOptions(card, app.StableOptions)
+
+		for j, option := range displayOptions {
+			pterm.FgCyan.Printf("%d. %s\n", j+1, option)
+		}
+		app.waitForReveal(card, "Press Enter to see the correct answer(s)...")
+	} else if card.Essay {
+		pterm.FgYellow.Println("\n(Open question, think through your answer)")
+		app.waitForReveal(card, "Press Enter to see the model answer...")
+	} else {
+		app.waitForReveal(card, "Press Enter to see the answer...")
+	}
+	if app.RevealStyle == revealWait {
+		for {
+			ready, _ := pterm.DefaultInteractiveConfirm.
+				WithDefaultValue(true).
+				WithConfirmText("y").WithRejectText("n").
+				Show("Settled on your answer? Reveal it now")
+			if ready {
+				break
+			}
+		}
+	}
+
+	if len(card.CorrectAnswers) > 1 {
+		pterm.FgLightGreen.Println("\nCorrect answers:")
+		for _, ans := range card.CorrectAnswers {
+			if ans == card.PrimaryAnswer {
+				pterm.FgGreen.Println("- ", ans, "(best)")
+			} else {
+				pterm.FgGreen.Println("- ", ans)
+			}
+		}
+	} else if len(card.CorrectAnswers) == 1 {
+		pterm.FgLightGreen.Println("\nAnswer:", card.CorrectAnswers[0])
+	} else {
+		pterm.FgLightGreen.Println("\nAnswer:", card.Answer)
+	}
+	if card.Note != "" {
+		pterm.FgYellow.Println("Note:", card.Note)
+	}
+
+	switch app.schedulerFor(card) {
+	case schedulerSM2:
+		return promptQuality()
+	case schedulerFSRS:
+		return promptRating()
+	}
+
+	result, _ := pterm.DefaultInteractiveConfirm.
+		WithDefaultValue(true).
+		WithConfirmText("y").
+		WithRejectText("n").
+		Show("Did you get it right?")
+	if result {
+		return 4
+	}
+	return 1
+}
+
+// qualityGrades are the SM-2 self-grades, best first.
+var qualityGrades = []string{
+	"5 - Perfect, instant recall",
+	"4 - Correct after some hesitation",
+	"3 - Correct, but with serious difficulty",
+	"2 - Wrong, but the answer felt familiar",
+	"1 - Wrong, remembered once I saw it",
+	"0 - Complete blackout",
+}
+
+func promptQuality() int {
+	selected, _ := pterm.DefaultInteractiveSelect.
+		WithOptions(qualityGrades).
+		WithDefaultOption(qualityGrades[1]).
+		WithDefaultText("How well did you know it?").
+		Show()
+	quality, err := strconv.Atoi(strings.SplitN(selected, " ", 2)[0])
+	if err != nil {
+		return 1
+	}
+	return quality
+}
+
+// schedulerNames lists the scheduling algorithms a deck or card can use.
+var schedulerNames = []string{schedulerNone, schedulerSM2, schedulerLeitner, schedulerFSRS}
+
+// schedulerFor returns the scheduling algorithm for a card: its own
+// Scheduler field if set and valid, otherwise the one stored in the card's
+// deck file, otherwise the app default.
+func (app *FlashcardApp) schedulerFor(card Flashcard) string {
+	if slices.Contains(schedulerNames, card.Scheduler) {
+		return card.Scheduler
+	}
+	if stored := app.DeckScheduler(app.CardFile(card)); slices.Contains(schedulerNames, stored) {
+		return stored
+	}
+	return app.Scheduler
+}
+
+// scheduleCard updates the card's scheduling fields after a review graded
+// with quality 0-5 (3 and above counts as remembered).
+func (app *FlashcardApp) scheduleCard(card *Flashcard, quality int, now time.Time) {
+	scheduler := app.schedulerFor(*card)
+	switch scheduler {
+	case schedulerSM2:
+		scheduleSM2(card, quality, now)
+	case schedulerLeitner:
+		scheduleLeitner(card, quality >= 3, now)
+	case schedulerFSRS:
+		scheduleFSRS(card, quality, now)
+	default:
+		return
+	}
+
+	computed := card.Interval
+	if app.clampInterval(card, now) {
+		pterm.Info.Printf("Next review in %d day(s) (%s, clamped from %d).\n", card.Interval, scheduler, computed)
+		return
+	}
+	pterm.Info.Printf("Next review in %d day(s) (%s).\n", card.Interval, scheduler)
+}
+
+// intervalDays rounds -min-interval up and -max-interval down to whole days.
+func intervalDays(minInterval, maxInterval time.Duration) (minDays, maxDays int) {
+	return int(math.Ceil(minInterval.Hours() / 24)), int(maxInterval.Hours() / 24)
+}
+
+// clampInterval keeps the card's interval within the configured minimum and
+// maximum and reschedules it if needed. It reports whether it had to clamp.
+func (app *FlashcardApp) clampInterval(card *Flashcard, now time.Time) bool {
+	minDays, maxDays := intervalDays(app.MinInterval, app.MaxInterval)
+
+	interval := card.Interval
+	if minDays > 0 && interval < minDays {
+		interval = minDays
+	}
+	if app.MaxInterval > 0 && interval > maxDays {
+		interval = maxDays
+	}
+	if interval == card.Interval {
+		return false
+	}
+
+	card.Interval = interval
+	next := now.AddDate(0, 0, interval)
+	card.NextReview = &next
+	return true
+}
+
+// scheduleSM2 applies the SuperMemo 2 algorithm.
+func scheduleSM2(card *Flashcard, quality int, now time.Time) {
+	if card.EaseFactor == 0 {
+		card.EaseFactor = 2.5
+	}
+
+	if quality < 3 {
+		card.Repetitions = 0
+		card.Interval = 1
+	} else {
+		switch card.Repetitions {
+		case 0:
+			card.Interval = 1
+		case 1:
+			card.Interval = 6
+		default:
+			card.Interval = int(float64(card.Interval)*card.EaseFactor + 0.5)
+		}
+		card.Repetitions++
+	}
+
+	q := float64(5 - quality)
+	card.EaseFactor += 0.1 - q*(0.08+q*0.02)
+	if card.EaseFactor < 1.3 {
+		card.EaseFactor = 1.3
+	}
+
+	next := now.AddDate(0, 0, card.Interval)
+	card.NextReview = &next
+}
+
+// fsrsWeights are the default parameters of FSRS-4.5, as used by Anki.
+var fsrsWeights = [17]float64{
+	0.4872, 1.4003, 3.7145, 13.8206, 5.1618, 1.2298, 0.8975, 0.031, 1.6474,
+	0.1367, 1.0461, 2.1072, 0.0793, 0.3246, 1.587, 0.2272, 2.8755,
+}
+
+const (
+	fsrsRetention = 0.9 // chance of still remembering a card when it is due
+	fsrsDecay     = -0.5
+	fsrsFactor    = 19.0 / 81.0
+)
+
+// fsrsRatings are the FSRS answer buttons with the review quality 0-5 each
+// one is recorded as.
+var fsrsRatings = []struct {
+	Label   string
+	Quality int
+}{
+	{"Again - I didn't know it", 1},
+	{"Hard - Correct, but with serious difficulty", 3},
+	{"Good - Correct after some hesitation", 4},
+	{"Easy - Perfect, instant recall", 5},
+}
+
+func promptRating() int {
+	labels := make([]string, len(fsrsRatings))
+	for i, rating := range fsrsRatings {
+		labels[i] = rating.Label
+	}
+	selected, _ := pterm.DefaultInteractiveSelect.
+		WithOptions(labels).
+		WithDefaultOption(labels[2]).
+		WithDefaultText("How well did you know it?").
+		Show()
+	if index := slices.Index(labels, selected); index >= 0 {
+		return fsrsRatings[index].Quality
+	}
+	return 1
+}
+
+// fsrsGrade turns a review quality 0-5 into an FSRS rating: 1 (again),
+// 2 (hard), 3 (good) or 4 (easy).
+func fsrsGrade(quality int) int {
+	switch {
+	case quality < 3:
+		return 1
+	case quality == 3:
+		return 2
+	case quality == 4:
+		return 3
+	}
+	return 4
+}
+
+// scheduleFSRS applies the FSRS-4.5 algorithm: the card's stability is the
+// number of days after which it is still remembered with fsrsRetention, and
+// its difficulty (1-10) sets how fast stability grows.
+func scheduleFSRS(card *Flashcard, quality int, now time.Time) {
+	w := fsrsWeights
+	grade := float64(fsrsGrade(quality))
+	initialDifficulty := func(g float64) float64 {
+		return w[4] - (g-3)*w[5]
+	}
+
+	if card.Stability <= 0 {
+		card.Stability = w[int(grade)-1]
+		card.FSRSDifficulty = math.Min(math.Max(initialDifficulty(grade), 1), 10)
+	} else {
+		// The last review is derived from the current schedule, since
+		// LastReviewed has already been set to now by the caller.
+		elapsed := float64(card.Interval)
+		if card.NextReview != nil {
+			elapsed += now.Sub(*card.NextReview).Hours() / 24
+		}
+		elapsed = math.Max(elapsed, 0)
+		retrievability := math.Pow(1+fsrsFactor*elapsed/card.Stability, fsrsDecay)
+
+		d, s := card.FSRSDifficulty, card.Stability
+		if grade == 1 {
+			forgotten := w[11] * math.Pow(d, -w[12]) * (math.Pow(s+1, w[13]) - 1) * math.Exp(w[14]*(1-retrievability))
+			card.Stability = math.Min(forgotten, s)
+		} else {
+			bonus := 1.0
+			if grade == 2 {
+				bonus = w[15]
+			} else if grade == 4 {
+				bonus = w[16]
+			}
+			card.Stability = s * (1 + math.Exp(w[8])*(11-d)*math.Pow(s, -w[9])*(math.Exp(w[10]*(1-retrievability))-1)*bonus)
+		}
+		next := d - w[6]*(grade-3)
+		// Mean reversion pulls the difficulty towards D0(3), that of a
+		// first review rated good.
+		next = w[7]*initialDifficulty(3) + (1-w[7])*next
+		card.FSRSDifficulty = math.Min(math.Max(next, 1), 10)
+	}
+
+	if grade == 1 {
+		card.Repetitions = 0
+	} else {
+		card.Repetitions++
+	}
+	interval := card.Stability / fsrsFactor * (math.Pow(fsrsRetention, 1/fsrsDecay) - 1)
+	card.Interval = max(int(math.Round(interval)), 1)
+	next := now.AddDate(0, 0, card.Interval)
+	card.NextReview = &next
+}
+
+// scheduleLeitner moves the card up one box when remembered and back to
+// box 1 otherwise, then schedules it by the box interval.
+func scheduleLeitner(card *Flashcard, correct bool, now time.Time) {
+	card.Box = leitnerBox(*card)
+	if correct {
+		if card.Box < len(leitnerIntervals) {
+			card.Box++
+		}
+	} else {
+		card.Box = 1
+	}
+
+	card.Interval = leitnerIntervals[card.Box-1]
+	next := now.AddDate(0, 0, card.Interval)
+	card.NextReview = &next
+}
+
+// leitnerBox returns the card's box, counting cards that were never in a box
+// as box 1 and cards beyond the last box (after fewer boxes were configured)
+// as the last one.
+func leitnerBox(card Flashcard) int {
+	switch {
+	case card.Box < 1:
+		return 1
+	case card.Box > len(leitnerIntervals):
+		return len(leitnerIntervals)
+	}
+	return card.Box
+}
+
+// parseLeitnerIntervals reads a comma-separated list of review intervals in
+// days, one per box, such as "1,3,7,14,30".
+func parseLeitnerIntervals(value string) ([]int, error) {
+	intervals := []int{}
+	for _, field := range strings.Split(value, ",") {
+		days, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || days < 1 {
+			return nil, fmt.Errorf("invalid box interval '%s' (use whole days of at least 1)", strings.TrimSpace(field))
+		}
+		intervals = append(intervals, days)
+	}
+	if len(intervals) < 2 {
+		return nil, errors.New("at least two boxes are needed")
+	}
+	return intervals, nil
+}
+
+// showLeitnerBoxes prints how many cards each Leitner box holds and how
+// many of them are due. It returns the number of due cards.
+func (app *FlashcardApp) showLeitnerBoxes(now time.Time) int {
+	cards := make([]int, len(leitnerIntervals))
+	due := make([]int, len(leitnerIntervals))
+	totalDue := 0
+	for _, card := range app.Flashcards {
+		if app.schedulerFor(card) != schedulerLeitner {
+			continue
+		}
+		box := leitnerBox(card) - 1
+		cards[box]++
+		if app.isDue(card, now) {
+			due[box]++
+			totalDue++
+		}
+	}
+	tableData := pterm.TableData{{"Box", "Every", "Cards", "Due"}}
+	for i, days := range leitnerIntervals {
+		tableData = append(tableData, []string{strconv.Itoa(i + 1), fmt.Sprintf("%d day(s)", days), strconv.Itoa(cards[i]), strconv.Itoa(due[i])})
+	}
+	pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+	return totalDue
+}
+
+// reviewDueBoxes shows the Leitner boxes and reviews their due cards, i.e.
+// the due cards whose scheduler is already Leitner.
+func (app *FlashcardApp) reviewDueBoxes() {
+	if app.showLeitnerBoxes(time.Now()) == 0 {
+		pterm.Success.Println("No box is due right now. Well done!")
+		return
+	}
+	app.leitnerOnly = true
+	defer func() { app.leitnerOnly = false }()
+	app.reviewCards("", "")
+}
+
+// normalizeAnswer lowercases an answer, removes accents, strips surrounding
+// punctuation and collapses internal whitespace so formatting differences
+// don't matter.
+func normalizeAnswer(answer string) string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(answer) {
+		if !unicode.Is(unicode.Mn, r) {
+			b.WriteRune(r)
+		}
+	}
+	answer = strings.TrimFunc(b.String(), func(r rune) bool {
+		return unicode.IsPunct(r) || unicode.IsSpace(r)
+	})
+	return strings.ToLower(strings.Join(strings.Fields(answer), " "))
+}
+
+// fuzzyMatch reports whether given is within the allowed edit distance of
+// expected after normalization. The allowance is threshold times the length
+// of the expected answer, so short answers still need to be exact.
+func fuzzyMatch(given, expected string, threshold float64) bool {
+	given, expected = normalizeAnswer(given), normalizeAnswer(expected)
+	if given == expected {
+		return true
+	}
+	if given == "" {
+		return false
+	}
+	allowed := int(threshold * float64(len([]rune(expected))))
+	return levenshtein(given, expected) <= allowed
+}
+
+// sameAnswer compares a given answer with a correct one, ignoring case
+// unless CaseSensitive is set. With NumericAnswers set, two answers that are
+// both numbers are compared by value.
+func (app *FlashcardApp) sameAnswer(given, expected string) bool {
+	if app.NumericAnswers {
+		if a, ok := parseNumber(given); ok {
+			if b, ok := parseNumber(expected); ok {
+				return math.Abs(a-b) <= 1e-9*math.Max(1, math.Max(math.Abs(a), math.Abs(b)))
+			}
+		}
+	}
+	if app.CaseSensitive {
+		return given == expected
+	}
+	return strings.EqualFold(given, expected)
+}
+
+var (
+	plainNumber    = regexp.MustCompile(`^[+-]?(\d+\.?\d*|\.\d+)([eE][+-]?\d+)?$`)
+	thousandsGroup = regexp.MustCompile(`^[+-]?\d{1,3}(,\d{3})+(\.\d*)?$`)
+)
+
+// parseNumber reads a plain decimal number such as "3", "3.0", "-0.5" or
+// "1,000" (commas only as thousands separators). Anything else, including
+// "0x10", "inf" or "3,5", is not a number.
+func parseNumber(s string) (float64, bool) {
+	s = strings.TrimSpace(s)
+	if thousandsGroup.MatchString(s) {
+		s = strings.ReplaceAll(s, ",", "")
+	}
+	if !plainNumber.MatchString(s) {
+		return 0, false
+	}
+	value, err := strconv.ParseFloat(s, 64)
+	return value, err == nil
+}
+
+// missingAnswers returns the required answers not named in the
+// comma-separated list given, in any order and ignoring case. With Fuzzy
+// set, names close to an answer count as well.
+func (app *FlashcardApp) missingAnswers(given string, required []string) []string {
+	named := []string{}
+	for _, part := range strings.Split(given, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			named = append(named, part)
+		}
+	}
+
+	missing := []string{}
+	for _, answer := range required {
+		found := false
+		for _, name := range named {
+			if app.sameAnswer(name, answer) || (app.Fuzzy && fuzzyMatch(name, answer, app.FuzzyThreshold)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, answer)
+		}
+	}
+	return missing
+}
+
+// levenshtein returns the edit distance between a and b in runes.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+// printOptionNotes explains every option of a multiple-choice card after it
+// was answered: correct options in green, wrong ones in red, each with its
+// note, and the user's pick marked.
+func printOptionNotes(card Flashcard, displayOptions []string, userAnswer string) {
+	pterm.FgLightBlue.Println("Why each option is right or wrong:")
+	for _, option := range displayOptions {
+		correct := false
+		for _, answer := range card.CorrectAnswers {
+			if strings.EqualFold(option, answer) {
+				correct = true
+				break
+			}
+		}
+
+		line := option
+		if note := card.OptionNotes[option]; note != "" {
+			line += " — " + note
+		}
+		if strings.EqualFold(option, userAnswer) {
+			line += " ← your answer"
+		}
+		if correct {
+			pterm.FgGreen.Println("  ✓ " + line)
+		} else {
+			pterm.FgRed.Println("  ✗ " + line)
+		}
+	}
+}
+
+// answerCredit returns the points for a correct answer: full credit unless
+// the card names a primary answer and a merely acceptable one was given.
+func answerCredit(card Flashcard, userAnswer string, partialCredit float64) float64 {
+	if card.PrimaryAnswer == "" || isSelectAll(card) || strings.EqualFold(userAnswer, card.PrimaryAnswer) {
+		return 1
+	}
+	return partialCredit
+}
+
+// acceptableAnswers returns the correct answers other than the primary one.
+func acceptableAnswers(card Flashcard) []string {
+	acceptable := []string{}
+	for _, answer := range card.CorrectAnswers {
+		if answer != card.PrimaryAnswer {
+			acceptable = append(acceptable, answer)
+		}
+	}
+	return acceptable
+}
+
+func formatPoints(points float64) string {
+	return strconv.FormatFloat(points, 'f', -1, 64)
+}
+
+// shuffledOptions returns a shuffled copy of the card's options. With
+// stable set, the order is derived from the card ID so a card always shows
+// the same layout across views and sessions.
+func shuffledOptions(card Flashcard, stable bool) []string {
+	options := make([]string, len(card.Options))
+	copy(options, card.Options)
+	shuffle := rng.Shuffle
+	if stable {
+		shuffle = rand.New(rand.NewSource(int64(card.ID))).Shuffle
+	}
+	shuffle(len(options), func(i, j int) {
+		options[i], options[j] = options[j], options[i]
+	})
+	return options
+}
+
+const (
+	flagChoice  = "[Flag this card]"
+	flagCommand = "!flag"
+	hintCommand = "!hint"
+)
+
+func (app *FlashcardApp) quizMode(categoryFilter, difficultyFilter string, numQuestions int) {
+	quizCardsSource := []Flashcard{}
+	if categoryFilter != "" {
+		for _, card := range app.Flashcards {
+			if strings.EqualFold(card.Category, categoryFilter) {
+				quizCardsSource = append(quizCardsSource, card)
+			}
+		}
+		pterm.Info.Printf("Starting quiz with cards from category '%s' in '%s'.\n", categoryFilter, app.FilePath)
+	} else {
+		quizCardsSource = app.Flashcards
+		pterm.Info.Printf("Starting quiz with cards from all categories in '%s'.\n", app.FilePath)
+	}
+	if difficultyFilter != "" {
+		quizCardsSource = deck.FilterByDifficulty(quizCardsSource, difficultyFilter)
+		pterm.Info.Printf("Only %s cards are used.\n", difficultyFilter)
+	}
+	if app.TagFilter != "" {
+		quizCardsSource = deck.FilterByTag(quizCardsSource, app.TagFilter)
+		pterm.Info.Printf("Only cards tagged '%s' are used.\n", app.TagFilter)
+	}
+	if app.MistakesOnly {
+		quizCardsSource = deck.OnlyMistakes(quizCardsSource)
+		pterm.Info.Println("Only cards answered wrong last time are used.")
+	}
+	if app.DueOnly {
+		quizCardsSource = app.onlyDue(quizCardsSource, time.Now())
+	}
+
+	if len(quizCardsSource) == 0 {
+		pterm.Warning.Println("No cards available for the quiz in this selection.")
+		return
+	}
+	quizCardsSource, held := app.applyDailyLimits(app.sessionOrder(quizCardsSource))
+	// Only the cards the quiz is short of count as held back; the rest
+	// would not have been asked anyway.
+	app.printHeldBack(min(held, max(numQuestions-len(quizCardsSource), 0)))
+	if len(quizCardsSource) == 0 {
+		pterm.Warning.Println("Today's limits are reached. Come back tomorrow!")
+		return
+	}
+	if numQuestions > len(quizCardsSource) {
+		numQuestions = len(quizCardsSource)
+		pterm.Info.Printf("Reduced quiz size to %d questions (maximum available).\n", numQuestions)
+	}
+	if numQuestions <= 0 {
+		pterm.Warning.Println("Number of questions must be positive.")
+		return
+	}
+
+	var quizCards []Flashcard
+	if app.Weighted {
+		quizCards = app.sessionOrder(selectWeighted(quizCardsSource, numQuestions))
+	} else {
+		quizCards = app.sessionOrder(quizCardsSource)[:numQuestions]
+	}
+	app.snapshotSession()
+
+	correctCount := 0
+	points := 0.0
+	results := []sessionResult{}
+
+	pterm.DefaultHeader.Printf("QUIZ MODE: %d questions from %s", numQuestions, app.FilePath)
+	if app.TimePerQuestion > 0 {
+		pterm.Info.Printf("Timed quiz: %s per question.\n", app.TimePerQuestion)
+	}
+	quizStart := time.Now()
+
+	for i, card := range quizCards {
+		printProgress(i+1, numQuestions)
+		pterm.DefaultSection.Printf("Question %d/%d", i+1, numQuestions)
+		shown := app.presentCard(card)
+		pterm.FgLightBlue.Println(shown.Question)
+		printAttachment(card)
+		shownAt := time.Now()
+
+		answer := app.askQuizQuestion(shown)
+		isMultipleChoice := len(card.Options) > 0
+		requireAll := app.RequireAll && !isMultipleChoice && len(shown.CorrectAnswers) > 1
+		isCorrect, userAnswer := answer.Correct, answer.Text
+
+		elapsed := time.Since(shownAt)
+
+		app.sessionMu.Lock()
+		originalIndex, found := app.CardIndex(card.ID)
+		if found {
+			now := time.Now()
+			app.Flashcards[originalIndex].TimesReviewed++
+			app.Flashcards[originalIndex].LastReviewed = &now
+			app.Flashcards[originalIndex].LastCorrect = &isCorrect
+			app.Flashcards[originalIndex].TotalTimeMs += elapsed.Milliseconds()
+			app.Flashcards[originalIndex].TimesAnswered++
+			if answer.Hinted {
+				app.Flashcards[originalIndex].TimesHinted++
+			}
+			if isCorrect && !answer.Hinted {
+				app.Flashcards[originalIndex].TimesCorrect++
+			}
+		}
+		app.sessionMu.Unlock()
+
+		if isCorrect {
+			credit := answerCredit(shown, userAnswer, app.PartialCredit)
+			if requireAll {
+				credit = 1
+			}
+			if answer.Hinted {
+				credit = math.Min(credit, app.PartialCredit)
+				pterm.Success.Printf("Correct with a hint (%s point).\n", formatPoints(credit))
+			} else if credit < 1 {
+				pterm.Success.Printf("Correct, but the best answer is: %s (%s point)\n", shown.PrimaryAnswer, formatPoints(credit))
+			} else {
+				pterm.Success.Println("Correct! ✓")
+			}
+			points += credit
+			correctCount++
+		} else {
+			printMissedAnswer(shown, answer.TimedOut)
+		}
+		if isMultipleChoice && len(card.OptionNotes) > 0 {
+			printOptionNotes(card, answer.Options, userAnswer)
+		}
+		results = append(results, sessionResult{Card: card, Correct: isCorrect})
+		time.Sleep(app.Delay)
+		fmt.Println()
+	}
+
+	app.sessionMu.Lock()
+	err := app.saveFlashcards()
+	app.inSession = false
+	app.sessionMu.Unlock()
+	if err != nil {
+		pterm.Error.Println("Failed to save quiz results.")
+	}
+	app.recordStudyDay()
+	app.recordDailyCounts(results)
+
+	score := 0.0
+	if numQuestions > 0 {
+		score = (points / float64(numQuestions)) * 100
+	}
+	pterm.Info.Printf("Quiz complete! You scored %s/%d (%.1f%%) in %s.\n", formatPoints(points), numQuestions, score, time.Since(quizStart).Round(time.Second))
+	app.recordQuizResult(categoryFilter, score, numQuestions)
+	app.recordSession("quiz", categoryFilter, numQuestions, correctCount)
+	app.printSessionSummary(results)
+	app.offerMissedDeck(results)
+}
+
+// offerMissedDeck offers to save the cards missed in a session as a new deck
+// for focused study, or congratulates when none were missed.
+func (app *FlashcardApp) offerMissedDeck(results []sessionResult) {
+	missed := []Flashcard{}
+	for _, result := range results {
+		if result.Correct {
+			continue
+		}
+		if index, found := app.CardIndex(result.Card.ID); found {
+			missed = append(missed, app.Flashcards[index])
+		}
+	}
+	if len(missed) == 0 {
+		pterm.Success.Println("No wrong answers this time. Well done!")
+		return
+	}
+
+	save, _ := pterm.DefaultInteractiveConfirm.
+		WithDefaultValue(false).
+		WithConfirmText("y").WithRejectText("n").
+		Show(fmt.Sprintf("Save the %d missed cards as a new deck?", len(missed)))
+	if !save {
+		return
+	}
+	base := strings.TrimSuffix(filepath.Base(app.FilePath), filepath.Ext(app.FilePath))
+	defaultPath := unusedPath(filepath.Join(filepath.Dir(app.FilePath), fmt.Sprintf("%s-missed-%s.json", base, time.Now().Format("2006-01-02"))))
+	destPath, _ := pterm.DefaultInteractiveTextInput.
+		WithDefaultValue(defaultPath).
+		Show("File name for the new deck")
+	destPath = strings.TrimSpace(destPath)
+	if destPath == "" {
+		return
+	}
+	replace := false
+	if _, err := os.Stat(destPath); err == nil {
+		replace, _ = pterm.DefaultInteractiveConfirm.
+			WithDefaultValue(false).
+			WithConfirmText("y").WithRejectText("n").
+			Show(fmt.Sprintf("'%s' already exists. Replace it?", destPath))
+		if !replace {
+			return
+		}
+	}
+	if app.DryRun {
+		pterm.Info.Printf("Dry run: would write %d missed cards to '%s'.\n", len(missed), destPath)
+		return
+	}
+	count, err := app.writeNewDeck(missed, destPath, replace)
+	if err != nil {
+		pterm.Error.Printf("Could not write '%s': %v\n", destPath, err)
+		return
+	}
+	pterm.Success.Printf("Saved %d missed cards to '%s'. Study them with --file %s.\n", count, destPath, destPath)
+}
+
+// unusedPath returns path, or when that file exists the first of
+// "name-2.ext", "name-3.ext", ... that doesn't.
+func unusedPath(path string) string {
+	ext := filepath.Ext(path)
+	stem := strings.TrimSuffix(path, ext)
+	for n := 2; ; n++ {
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			return path
+		}
+		path = fmt.Sprintf("%s-%d%s", stem, n, ext)
+	}
+}
+
+// cramMode quizzes the selected cards over and over, dropping each card once
+// it is answered correctly, until none are left. Only the first attempt at
+// each card counts towards its stats.
+func (app *FlashcardApp) cramMode(categoryFilter string) {
+	remaining := deck.FilterByCategory(app.Flashcards, categoryFilter)
+	if app.TagFilter != "" {
+		remaining = deck.FilterByTag(remaining, app.TagFilter)
+	}
+	if len(remaining) == 0 {
+		pterm.Warning.Println("No cards to cram in this selection.")
+		return
+	}
+	app.snapshotSession()
+	total := len(remaining)
+	pterm.DefaultHeader.Printf("CRAM MODE: %d cards from %s", total, app.FilePath)
+
+	firstCorrect := 0
+	round := 0
+	for len(remaining) > 0 {
+		round++
+		remaining = app.sessionOrder(remaining)
+
+		wrong := []Flashcard{}
+		for i, card := range remaining {
+			pterm.DefaultSection.Printf("Round %d - Card %d/%d (%d of %d cleared)", round, i+1, len(remaining), total-len(remaining), total)
+			pterm.FgLightBlue.Println(card.Question)
+			printAttachment(card)
+			shownAt := time.Now()
+			answer := app.askQuizQuestion(card)
+
+			if round == 1 {
+				app.sessionMu.Lock()
+				if index, found := app.CardIndex(card.ID); found {
+					now := time.Now()
+					correct := answer.Correct
+					app.Flashcards[index].TimesReviewed++
+					app.Flashcards[index].LastReviewed = &now
+					app.Flashcards[index].LastCorrect = &correct
+					app.Flashcards[index].TotalTimeMs += time.Since(shownAt).Milliseconds()
+					app.Flashcards[index].TimesAnswered++
+					if answer.Hinted {
+						app.Flashcards[index].TimesHinted++
+					}
+					if answer.Correct && !answer.Hinted {
+						app.Flashcards[index].TimesCorrect++
+					}
+				}
+				app.sessionMu.Unlock()
+				if answer.Correct {
+					firstCorrect++
+				}
+			}
+
+			if answer.Correct {
+				pterm.Success.Println("Correct! ✓")
+			} else {
+				printMissedAnswer(card, answer.TimedOut)
+				wrong = append(wrong, card)
+			}
+			time.Sleep(app.Delay)
+			fmt.Println()
+		}
+
+		if round == 1 {
+			app.sessionMu.Lock()
+			err := app.saveFlashcards()
+			app.inSession = false
+			app.sessionMu.Unlock()
+			if err != nil {
+				pterm.Error.Println("Failed to save cram results.")
+			}
+			app.recordStudyDay()
+		}
+		if len(wrong) > 0 {
+			pterm.Info.Printf("%d cards left, starting round %d.\n", len(wrong), round+1)
+		}
+		remaining = wrong
+	}
+
+	pterm.Success.Printf("Cleared all %d cards in %d round(s). First attempt: %d/%d correct.\n", total, round, firstCorrect, total)
+}
+
+// presentCard returns the card the way review and quiz should ask it: as
+// is, or reversed in Reverse mode. Multiple-choice cards can't be reversed
+// and are asked the normal way with a note.
+func (app *FlashcardApp) presentCard(card Flashcard) Flashcard {
+	if !app.Reverse {
+		return card
+	}
+	if len(card.Options) > 0 {
+		pterm.Info.Println("Multiple-choice cards can't be reversed; this one is asked the normal way.")
+		return card
+	}
+	if card.Essay {
+		pterm.Info.Println("Open questions can't be reversed; this one is asked the normal way.")
+		return card
+	}
+	return reversed(card)
+}
+
+// reversed swaps a text card's sides: the answers become the question and
+// the question the only correct answer. The ID is kept so stats still land
+// on the original card.
+func reversed(card Flashcard) Flashcard {
+	answers := card.CorrectAnswers
+	if len(answers) == 0 {
+		answers = []string{card.Answer}
+	}
+	card.Question, card.Answer = strings.Join(answers, " / "), card.Question
+	card.CorrectAnswers = []string{card.Answer}
+	card.PrimaryAnswer = ""
+	return card
+}
+
+// quizAnswer is the outcome of one quiz question.
+type quizAnswer struct {
+	Text     string
+	Correct  bool
+	TimedOut bool
+	Hinted   bool     // a hint was shown before answering
+	Options  []string // multiple-choice options in the order shown
+}
+
+// numberedOptions labels the options of a multiple-choice card "1. ...",
+// "2. ..." for the answer menu.
+func numberedOptions(options []string) []string {
+	labels := make([]string, 0, len(options))
+	for i, option := range options {
+		labels = append(labels, fmt.Sprintf("%d. %s", i+1, option))
+	}
+	return labels
+}
+
+// chosenOption maps the label picked from the answer menu back to its
+// option by position; parsing the label would break on options that contain
+// ". " themselves. Choices past the options, like flagChoice, give "".
+func chosenOption(labels, options []string, selected string) string {
+	if index := slices.Index(labels, selected); index >= 0 && index < len(options) {
+		return options[index]
+	}
+	return ""
+}
+
+// askQuizQuestion asks for the answer to a card whose question is already
+// shown, by selection for multiple choice and typed otherwise, and checks
+// it. Typed answers honour RequireAll and Fuzzy; TimePerQuestion limits
+// both.
+func (app *FlashcardApp) askQuizQuestion(card Flashcard) quizAnswer {
+	if card.Essay {
+		return app.askEssayQuestion(card)
+	}
+	isMultipleChoice := len(card.Options) > 0
+	requireAll := app.RequireAll && !isMultipleChoice && len(card.CorrectAnswers) > 1
+	isCorrect := false
+	timedOut := false
+	hinted := false
+	var userAnswer string
+	var displayOptions []string
+
+	if isMultipleChoice {
+		displayOptions = shuffledOptions(card, app.StableOptions)
+
+		if isSelectAll(card) {
+			var selected []string
+			selected, timedOut = app.askSelectAll(card.ID, displayOptions)
+			userAnswer = strings.Join(selected, ", ")
+			missed, wrong := app.compareSelection(selected, card.CorrectAnswers)
+			isCorrect = !timedOut && len(missed) == 0 && len(wrong) == 0
+			if !isCorrect && !timedOut {
+				if len(wrong) > 0 {
+					pterm.Warning.Printf("Wrongly selected: %s\n", strings.Join(wrong, ", "))
+				}
+				if len(missed) > 0 {
+					pterm.Warning.Printf("Not selected: %s\n", strings.Join(missed, ", "))
+				}
+			}
+		} else if app.NumericMC {
+			userAnswer, timedOut = promptWithTimeout(app.TimePerQuestion, func() string {
+				return app.promptOptionNumber(card.ID, displayOptions)
+			})
+		} else {
+			optionChoices := append(numberedOptions(displayOptions), flagChoice)
+
+			selectedOptionStr, expired := promptWithTimeout(app.TimePerQuestion, func() string {
+				for {
+					selected, _ := pterm.DefaultInteractiveSelect.
+						WithOptions(optionChoices).
+						WithDefaultText("Select your answer").
+						Show()
+					if selected != flagChoice {
+						return selected
+					}
+					app.promptFlag(card.ID)
+				}
+			})
+			timedOut = expired
+			userAnswer = chosenOption(optionChoices, displayOptions, selectedOptionStr)
+		}
+
+		for _, correctAnswer := range card.CorrectAnswers {
+			if isSelectAll(card) || timedOut {
+				break
+			}
+			if app.sameAnswer(userAnswer, correctAnswer) {
+				isCorrect = true
+				break
+			}
+		}
+
+	} else {
+		prompt := "Your answer ('" + hintCommand + "' for a clue, '" + flagCommand + "' to flag this card)"
+		if requireAll {
+			prompt = fmt.Sprintf("Name all %d answers, separated by commas ('%s' for a clue, '%s' to flag this card)", len(card.CorrectAnswers), hintCommand, flagCommand)
+		}
+		userAnswer, timedOut = promptWithTimeout(app.TimePerQuestion, func() string {
+			for {
+				answer, _ := pterm.DefaultInteractiveTextInput.Show(prompt)
+				answer = strings.TrimSpace(answer)
+				switch {
+				case strings.EqualFold(answer, flagCommand):
+					app.promptFlag(card.ID)
+				case strings.EqualFold(answer, hintCommand):
+					hinted = true
+					pterm.Info.Println("Hint:", answerHint(card))
+				default:
+					return answer
+				}
+			}
+		})
+		if timedOut {
+			userAnswer = ""
+		}
+
+		closeTo := ""
+		if requireAll && !timedOut {
+			missing := app.missingAnswers(userAnswer, card.CorrectAnswers)
+			isCorrect = len(missing) == 0
+			if !isCorrect && len(missing) < len(card.CorrectAnswers) {
+				pterm.Warning.Printf("Partly right. You missed: %s\n", strings.Join(missing, ", "))
+			}
+		}
+		for _, correctAnswer := range card.CorrectAnswers {
+			if timedOut || requireAll {
+				break
+			}
+			if app.sameAnswer(userAnswer, correctAnswer) {
+				isCorrect = true
+				closeTo = ""
+				break
+			}
+			if app.Fuzzy && closeTo == "" && fuzzyMatch(userAnswer, correctAnswer, app.FuzzyThreshold) {
+				closeTo = correctAnswer
+			}
+		}
+		if !isCorrect && closeTo != "" {
+			isCorrect = true
+			pterm.Info.Printf("Close enough! The exact answer was: %s\n", closeTo)
+		}
+	}
+
+	return quizAnswer{Text: userAnswer, Correct: isCorrect, TimedOut: timedOut, Hinted: hinted, Options: displayOptions}
+}
+
+// isSelectAll reports whether a card is a "select all that apply" question:
+// a multiple-choice card marked MultiSelect with more than one correct
+// option.
+func isSelectAll(card Flashcard) bool {
+	return card.MultiSelect && len(card.Options) > 0 && len(card.CorrectAnswers) > 1
+}
+
+// askSelectAll lets the user pick any number of options, with a checklist or,
+// with NumericMC, by typing their numbers separated by commas.
+func (app *FlashcardApp) askSelectAll(cardID int, options []string) ([]string, bool) {
+	// The selection is passed through promptWithTimeout as one string;
+	// options can't contain line breaks.
+	joined, timedOut := promptWithTimeout(app.TimePerQuestion, func() string {
+		if app.NumericMC {
+			return strings.Join(app.promptOptionNumbers(cardID, options), "\n")
+		}
+		selected, _ := pterm.DefaultInteractiveMultiselect.
+			WithOptions(options).
+			WithMaxHeight(len(options)).
+			WithDefaultText("Select all that apply (Enter toggles, Tab submits)").
+			Show()
+		return strings.Join(selected, "\n")
+	})
+	if timedOut || joined == "" {
+		return nil, timedOut
+	}
+	return strings.Split(joined, "\n"), false
+}
+
+// promptOptionNumbers is promptOptionNumber for select-all questions: it
+// asks for comma-separated option numbers and returns the chosen options.
+func (app *FlashcardApp) promptOptionNumbers(cardID int, options []string) []string {
+	for j, option := range options {
+		pterm.FgCyan.Printf("%d. %s\n", j+1, option)
+	}
+	prompt := fmt.Sprintf("Select all that apply: numbers 1-%d separated by commas ('%s' to flag this card)", len(options), flagCommand)
+	for {
+		input, _ := pterm.DefaultInteractiveTextInput.Show(prompt)
+		input = strings.TrimSpace(input)
+		if strings.EqualFold(input, flagCommand) {
+			app.promptFlag(cardID)
+			continue
+		}
+		selected := []string{}
+		valid := true
+		for _, part := range strings.Split(input, ",") {
+			number, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil || number < 1 || number > len(options) {
+				valid = false
+				break
+			}
+			if !deck.ContainsFold(selected, options[number-1]) {
+				selected = append(selected, options[number-1])
+			}
+		}
+		if valid {
+			return selected
+		}
+		pterm.Warning.Printf("Please enter numbers from 1 to %d, e.g. 1,3.\n", len(options))
+	}
+}
+
+// compareSelection returns the correct options missing from selected and
+// the selected options that are not correct.
+func (app *FlashcardApp) compareSelection(selected, correct []string) (missed, wrong []string) {
+	for _, answer := range correct {
+		found := false
+		for _, choice := range selected {
+			if app.sameAnswer(choice, answer) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missed = append(missed, answer)
+		}
+	}
+	for _, choice := range selected {
+		found := false
+		for _, answer := range correct {
+			if app.sameAnswer(choice, answer) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			wrong = append(wrong, choice)
+		}
+	}
+	return missed, wrong
+}
+
+// promptOptionNumber prints the numbered options and asks for the number of
+// the chosen one until a valid number is typed, for terminals where arrow
+// keys don't work. It returns the text of the chosen option.
+func (app *FlashcardApp) promptOptionNumber(cardID int, options []string) string {
+	for j, option := range options {
+		pterm.FgCyan.Printf("%d. %s\n", j+1, option)
+	}
+	prompt := fmt.Sprintf("Your answer (1-%d, '%s' to flag this card)", len(options), flagCommand)
+	for {
+		input, _ := pterm.DefaultInteractiveTextInput.Show(prompt)
+		input = strings.TrimSpace(input)
+		if strings.EqualFold(input, flagCommand) {
+			app.promptFlag(cardID)
+			continue
+		}
+		number, err := strconv.Atoi(input)
+		if err != nil || number < 1 || number > len(options) {
+			pterm.Warning.Printf("Please enter a number from 1 to %d.\n", len(options))
+			continue
+		}
+		return options[number-1]
+	}
+}
+
+// askEssayQuestion lets the user write or think through an answer to an
+// open question, shows the model answer and asks for a self-grade. Nothing
+// is compared automatically.
+func (app *FlashcardApp) askEssayQuestion(card Flashcard) quizAnswer {
+	userAnswer, timedOut := promptWithTimeout(app.TimePerQuestion, func() string {
+		for {
+			answer, _ := pterm.DefaultInteractiveTextInput.
+				Show("Write or think through your answer, then press Enter ('" + flagCommand + "' to flag this card)")
+			if !strings.EqualFold(strings.TrimSpace(answer), flagCommand) {
+				return strings.TrimSpace(answer)
+			}
+			app.promptFlag(card.ID)
+		}
+	})
+	if timedOut {
+		return quizAnswer{TimedOut: true}
+	}
+
+	pterm.DefaultBox.WithTitle("Model answer").Println(card.Answer)
+	knewIt, _ := pterm.DefaultInteractiveConfirm.
+		WithConfirmText("y").WithRejectText("n").
+		Show("Did your answer cover it?")
+	return quizAnswer{Text: userAnswer, Correct: knewIt}
+}
+
+// answerHint shows the first letter and length of a card's answer, e.g.
+// "M _ _ _ _ _ (6 letters)". Spaces between words are kept.
+func answerHint(card Flashcard) string {
+	answer := card.PrimaryAnswer
+	if answer == "" && len(card.CorrectAnswers) > 0 {
+		answer = card.CorrectAnswers[0]
+	}
+	if answer == "" {
+		answer = card.Answer
+	}
+
+	parts := []string{}
+	letters := 0
+	for i, r := range []rune(strings.TrimSpace(answer)) {
+		switch {
+		case unicode.IsSpace(r):
+			parts = append(parts, " ")
+		case i == 0:
+			parts = append(parts, string(r))
+			letters++
+		default:
+			parts = append(parts, "_")
+			letters++
+		}
+	}
+	return fmt.Sprintf("%s (%d letters)", strings.Join(parts, " "), letters)
+}
+
+// printMissedAnswer tells the user their quiz answer was wrong (or too
+// late) and shows the correct one(s).
+func printMissedAnswer(card Flashcard, timedOut bool) {
+	if card.Note != "" {
+		defer pterm.FgYellow.Println("Note:", card.Note)
+	}
+	if card.Essay && !timedOut {
+		pterm.Error.Println("Marked as missed.")
+		return
+	}
+	if timedOut {
+		pterm.Error.Print("Time's up! ")
+	} else {
+		pterm.Error.Print("Incorrect. ")
+	}
+	if len(card.CorrectAnswers) > 1 && card.PrimaryAnswer != "" && !isSelectAll(card) {
+		pterm.FgRed.Printf("The best answer was: %s (also acceptable: %s)\n", card.PrimaryAnswer, strings.Join(acceptableAnswers(card), ", "))
+	} else if len(card.CorrectAnswers) > 1 {
+		pterm.FgRed.Printf("The correct answers were: %s\n", strings.Join(card.CorrectAnswers, ", "))
+	} else if len(card.CorrectAnswers) == 1 {
+		pterm.FgRed.Printf("The correct answer was: %s\n", card.CorrectAnswers[0])
+	} else {
+		pterm.FgRed.Printf("The correct answer was: %s\n", card.Answer)
+	}
+}
+
+// promptWithTimeout runs prompt and returns its answer, or reports a
+// timeout once limit has passed (a limit of 0 waits forever). pterm prompts
+// can't be cancelled, so after a timeout the open prompt still has to be
+// dismissed before the quiz continues.
+func promptWithTimeout(limit time.Duration, prompt func() string) (string, bool) {
+	if limit <= 0 {
+		return prompt(), false
+	}
+	answer := make(chan string, 1)
+	go func() {
+		answer <- prompt()
+	}()
+	select {
+	case a := <-answer:
+		return a, false
+	case <-time.After(limit):
+		fmt.Println()
+		pterm.Warning.Println("Time's up! Press Enter to see the answer.")
+		<-answer
+		return "", true
+	}
+}
+
+// QuizResult is one finished quiz, kept in scores.json for the leaderboard.
+type QuizResult struct {
+	Deck      string    `json:"deck"`
+	Category  string    `json:"category"`
+	Score     float64   `json:"score_percent"`
+	Questions int       `json:"questions"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// scoresPath is the scores.json file next to the deck. Decks in the same
+// directory share it; results are told apart by their Deck field.
+func (app *FlashcardApp) scoresPath() string {
+	return filepath.Join(filepath.Dir(app.FilePath), "scores.json")
+}
+
+// loadScores reads scores.json. A missing or empty file has no scores; a
+// file that can't be read or decoded is an error, so it isn't overwritten.
+func (app *FlashcardApp) loadScores() ([]QuizResult, error) {
+	data, err := ioutil.ReadFile(app.scoresPath())
+	if errors.Is(err, os.ErrNotExist) || (err == nil && len(data) == 0) {
+		return []QuizResult{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var results []QuizResult
+	if err := json.Unmarshal(data, &results); err != nil {
+		return nil, fmt.Errorf("decoding '%s': %w", app.scoresPath(), err)
+	}
+	return results, nil
+}
+
+// recordQuizResult appends a finished quiz to scores.json. An empty
+// category is stored as "All".
+func (app *FlashcardApp) recordQuizResult(category string, score float64, questions int) {
+	if app.DryRun {
+		return
+	}
+	if category == "" {
+		category = "All"
+	}
+	results, err := app.loadScores()
+	if err != nil {
+		pterm.Error.Printf("Could not load the quiz scores: %v\n", err)
+		pterm.Warning.Printf("This score was not saved, so '%s' is left as it is.\n", app.scoresPath())
+		return
+	}
+	results = append(results, QuizResult{
+		Deck:      filepath.Base(app.FilePath),
+		Category:  category,
+		Score:     score,
+		Questions: questions,
+		Timestamp: time.Now(),
+	})
+	data, err := json.MarshalIndent(results, "", "  ")
+	if err == nil {
+		err = ioutil.WriteFile(app.scoresPath(), data, 0644)
+	}
+	if err != nil {
+		pterm.Warning.Printf("Could not save quiz score to '%s': %v\n", app.scoresPath(), err)
+	}
+}
+
+// showLeaderboard prints the three best quiz scores of this deck per
+// category. Ties go to the quiz with more questions, then the earlier one.
+func (app *FlashcardApp) showLeaderboard() {
+	deckName := filepath.Base(app.FilePath)
+	scores, err := app.loadScores()
+	if err != nil {
+		pterm.Error.Printf("Could not load the quiz scores: %v\n", err)
+		return
+	}
+	byCategory := map[string][]QuizResult{}
+	for _, result := range scores {
+		if result.Deck == deckName {
+			byCategory[result.Category] = append(byCategory[result.Category], result)
+		}
+	}
+	if len(byCategory) == 0 {
+		pterm.Info.Println("No quiz scores yet. Finish a quiz to get on the leaderboard.")
+		return
+	}
+
+	categories := make([]string, 0, len(byCategory))
+	for category := range byCategory {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+
+	tableData := pterm.TableData{{"Category", "Rank", "Score", "Questions", "Date"}}
+	for _, category := range categories {
+		results := byCategory[category]
+		sort.SliceStable(results, func(i, j int) bool {
+			if results[i].Score != results[j].Score {
+				return results[i].Score > results[j].Score
+			}
+			if results[i].Questions != results[j].Questions {
+				return results[i].Questions > results[j].Questions
+			}
+			return results[i].Timestamp.Before(results[j].Timestamp)
+		})
+		for rank, result := range results {
+			if rank == 3 {
+				break
+			}
+			tableData = append(tableData, []string{
+				category,
+				strconv.Itoa(rank + 1),
+				fmt.Sprintf("%.1f%%", result.Score),
+				strconv.Itoa(result.Questions),
+				result.Timestamp.Format("2006-01-02 15:04"),
+			})
+		}
+	}
+	pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+}
+
+// sessionRecord is one finished review or quiz, kept in history.json for
+// the accuracy trend.
+type sessionRecord struct {
+	Deck      string    `json:"deck"`
+	Mode      string    `json:"mode"`
+	Category  string    `json:"category"`
+	Cards     int       `json:"cards"`
+	Correct   int       `json:"correct"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// maxHistory caps history.json; older sessions are dropped first.
+const maxHistory = 500
+
+// historyPath is the history.json file next to the deck. Like scores.json
+// it is shared by the decks in that directory.
+func (app *FlashcardApp) historyPath() string {
+	return filepath.Join(filepath.Dir(app.FilePath), "history.json")
+}
+
+// loadHistory reads history.json. A missing or empty file has no sessions;
+// a file that can't be read or decoded is an error, so it isn't overwritten.
+func (app *FlashcardApp) loadHistory() ([]sessionRecord, error) {
+	data, err := ioutil.ReadFile(app.historyPath())
+	if errors.Is(err, os.ErrNotExist) || (err == nil && len(data) == 0) {
+		return []sessionRecord{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var records []sessionRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("decoding '%s': %w", app.historyPath(), err)
+	}
+	return records, nil
+}
+
+// recordSession appends a finished session to history.json, keeping only
+// the last maxHistory sessions. An empty category is stored as "All".
+func (app *FlashcardApp) recordSession(mode, category string, cards, correct int) {
+	if app.DryRun || cards == 0 {
+		return
+	}
+	if category == "" {
+		category = "All"
+	}
+	records, err := app.loadHistory()
+	if err != nil {
+		pterm.Error.Printf("Could not load the session history: %v\n", err)
+		pterm.Warning.Printf("This session was not added, so '%s' is left as it is.\n", app.historyPath())
+		return
+	}
+	records = append(records, sessionRecord{
+		Deck:      filepath.Base(app.FilePath),
+		Mode:      mode,
+		Category:  category,
+		Cards:     cards,
+		Correct:   correct,
+		Timestamp: time.Now(),
+	})
+	if len(records) > maxHistory {
+		records = records[len(records)-maxHistory:]
+	}
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err == nil {
+		err = ioutil.WriteFile(app.historyPath(), data, 0644)
+	}
+	if err != nil {
+		pterm.Warning.Printf("Could not save session history to '%s': %v\n", app.historyPath(), err)
+	}
+}
+
+// showTrend prints this deck's accuracy per week over the last 12 weeks
+// with sessions, oldest first.
+func (app *FlashcardApp) showTrend() {
+	type week struct {
+		Label                    string
+		Sessions, Cards, Correct int
+	}
+	deckName := filepath.Base(app.FilePath)
+	weeks := []*week{}
+	byLabel := map[string]*week{}
+	records, err := app.loadHistory()
+	if err != nil {
+		pterm.Error.Printf("Could not load the session history: %v\n", err)
+		return
+	}
+	sort.SliceStable(records, func(i, j int) bool { return records[i].Timestamp.Before(records[j].Timestamp) })
+	for _, record := range records {
+		if record.Deck != deckName {
+			continue
+		}
+		year, number := record.Timestamp.Local().ISOWeek()
+		label := fmt.Sprintf("%d-W%02d", year, number)
+		w, ok := byLabel[label]
+		if !ok {
+			w = &week{Label: label}
+			byLabel[label] = w
+			weeks = append(weeks, w)
+		}
+		w.Sessions++
+		w.Cards += record.Cards
+		w.Correct += record.Correct
+	}
+	if len(weeks) == 0 {
+		pterm.Info.Println("No sessions recorded yet. Finish a review or quiz to start the trend.")
+		return
+	}
+	if len(weeks) > 12 {
+		weeks = weeks[len(weeks)-12:]
+	}
+
+	const width = 20
+	tableData := pterm.TableData{{"Week", "Sessions", "Cards", "Accuracy", ""}}
+	for _, w := range weeks {
+		accuracy := float64(w.Correct) / float64(w.Cards)
+		filled := int(accuracy*width + 0.5)
+		tableData = append(tableData, []string{
+			w.Label,
+			strconv.Itoa(w.Sessions),
+			strconv.Itoa(w.Cards),
+			fmt.Sprintf("%.0f%%", accuracy*100),
+			pterm.FgCyan.Sprint(strings.Repeat("█", filled)) + pterm.FgGray.Sprint(strings.Repeat("█", width-filled)),
+		})
+	}
+	pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+}
+
+// lastCategoryPath is the file next to the deck that holds the category of
+// the last review, quiz or cram session started from the menu.
+func (app *FlashcardApp) lastCategoryPath() string {
+	return app.FilePath + ".category"
+}
+
+// loadLastCategory returns the remembered category, or "" if there is none
+// or it is turned off.
+func (app *FlashcardApp) loadLastCategory() string {
+	if !app.LastCategory {
+		return ""
+	}
+	data, err := ioutil.ReadFile(app.lastCategoryPath())
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// rememberCategory stores the category of a session for selectCategory to
+// pre-select next time; "" (all categories) clears it.
+func (app *FlashcardApp) rememberCategory(category string) {
+	if !app.LastCategory || app.DryRun {
+		return
+	}
+	if category == "" {
+		app.forgetCategory()
+		return
+	}
+	if err := ioutil.WriteFile(app.lastCategoryPath(), []byte(category+"\n"), 0644); err != nil {
+		pterm.Warning.Printf("Could not remember the category in '%s': %v\n", app.lastCategoryPath(), err)
+	}
+}
+
+// forgetCategory removes the remembered category.
+func (app *FlashcardApp) forgetCategory() {
+	if app.DryRun {
+		return
+	}
+	if err := os.Remove(app.lastCategoryPath()); err != nil && !errors.Is(err, os.ErrNotExist) {
+		pterm.Warning.Printf("Could not remove '%s': %v\n", app.lastCategoryPath(), err)
+	}
+}
+
+// studyDaysPath is the file next to the deck that lists the days on which
+// a review, quiz or cram session took place.
+func (app *FlashcardApp) studyDaysPath() string {
+	return app.FilePath + ".days"
+}
+
+// loadStudyDays returns the recorded study days as local "2006-01-02"
+// dates. Days on which a card was last reviewed count too, so decks from
+// before the days file existed start with a streak.
+func (app *FlashcardApp) loadStudyDays() map[string]bool {
+	days := make(map[string]bool)
+	for _, card := range app.Flashcards {
+		if card.LastReviewed != nil {
+			days[card.LastReviewed.Local().Format("2006-01-02")] = true
+		}
+	}
+	data, err := ioutil.ReadFile(app.studyDaysPath())
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			pterm.Warning.Printf("Could not read study days from '%s': %v\n", app.studyDaysPath(), err)
+		}
+		return days
+	}
+	var recorded []string
+	if err := json.Unmarshal(data, &recorded); err != nil {
+		pterm.Warning.Printf("Could not decode study days from '%s': %v\n", app.studyDaysPath(), err)
+		return days
+	}
+	for _, day := range recorded {
+		days[day] = true
+	}
+	return days
+}
+
+// recordStudyDay adds today to the study days file.
+func (app *FlashcardApp) recordStudyDay() {
+	if app.DryRun {
+		return
+	}
+	today := time.Now().Format("2006-01-02")
+	var recorded []string
+	if data, err := ioutil.ReadFile(app.studyDaysPath()); err == nil {
+		_ = json.Unmarshal(data, &recorded)
+	}
+	for _, day := range recorded {
+		if day == today {
+			return
+		}
+	}
+	data, err := json.MarshalIndent(append(recorded, today), "", "  ")
+	if err == nil {
+		err = ioutil.WriteFile(app.studyDaysPath(), data, 0644)
+	}
+	if err != nil {
+		pterm.Warning.Printf("Could not save study day to '%s': %v\n", app.studyDaysPath(), err)
+	}
+}
+
+// dailyCounts are the cards answered in review and quiz on one day, split
+// into cards seen for the first time and cards seen before.
+type dailyCounts struct {
+	Date    string `json:"date"`
+	New     int    `json:"new"`
+	Reviews int    `json:"reviews"`
+}
+
+// dailyCountsPath is the file next to the deck that keeps today's counts
+// for -new-per-day and -reviews-per-day across restarts.
+func (app *FlashcardApp) dailyCountsPath() string {
+	return app.FilePath + ".daily"
+}
+
+// loadDailyCounts returns today's counts; counts of an earlier day start
+// over at zero.
+func (app *FlashcardApp) loadDailyCounts() dailyCounts {
+	today := dailyCounts{Date: time.Now().Format("2006-01-02")}
+	data, err := ioutil.ReadFile(app.dailyCountsPath())
+	if err != nil {
+		return today
+	}
+	var counts dailyCounts
+	if err := json.Unmarshal(data, &counts); err != nil || counts.Date != today.Date {
+		return today
+	}
+	return counts
+}
+
+// applyDailyLimits drops the cards beyond what is left of today's new-card
+// and review limits, keeping the order of cards. A limit of 0 means none.
+// It returns the cards kept and the number held back.
+func (app *FlashcardApp) applyDailyLimits(cards []Flashcard) ([]Flashcard, int) {
+	if app.NewPerDay <= 0 && app.ReviewsPerDay <= 0 {
+		return cards, 0
+	}
+	counts := app.loadDailyCounts()
+	newLeft, reviewsLeft := -1, -1
+	if app.NewPerDay > 0 {
+		newLeft = max(app.NewPerDay-counts.New, 0)
+	}
+	if app.ReviewsPerDay > 0 {
+		reviewsLeft = max(app.ReviewsPerDay-counts.Reviews, 0)
+	}
+	kept := deck.DailyLimit(cards, newLeft, reviewsLeft)
+	return kept, len(cards) - len(kept)
+}
+
+// printHeldBack reports the cards applyDailyLimits held back for tomorrow.
+func (app *FlashcardApp) printHeldBack(held int) {
+	if held == 0 {
+		return
+	}
+	counts := app.loadDailyCounts()
+	pterm.Info.Printf("Held back %d cards for tomorrow (today so far: %d new, %d reviews; limits -new-per-day %d, -reviews-per-day %d).\n",
+		held, counts.New, counts.Reviews, app.NewPerDay, app.ReviewsPerDay)
+}
+
+// recordDailyCounts adds the cards of a finished session to today's counts.
+// Whether a card was new is judged by its state before the session.
+func (app *FlashcardApp) recordDailyCounts(results []sessionResult) {
+	if app.DryRun {
+		return
+	}
+	counts := app.loadDailyCounts()
+	for _, result := range results {
+		if result.Card.TimesReviewed == 0 {
+			counts.New++
+		} else {
+			counts.Reviews++
+		}
+	}
+	data, err := json.MarshalIndent(counts, "", "  ")
+	if err == nil {
+		err = ioutil.WriteFile(app.dailyCountsPath(), data, 0644)
+	}
+	if err != nil {
+		pterm.Warning.Printf("Could not save today's counts to '%s': %v\n", app.dailyCountsPath(), err)
+	}
+}
+
+// currentStreak counts the consecutive local calendar days with a study
+// session, ending today. A streak that ended yesterday still counts, as
+// today isn't over yet.
+func (app *FlashcardApp) currentStreak() int {
+	days := app.loadStudyDays()
+	now := time.Now()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 12, 0, 0, 0, time.Local)
+	if !days[day.Format("2006-01-02")] {
+		day = day.AddDate(0, 0, -1)
+	}
+	streak := 0
+	for days[day.Format("2006-01-02")] {
+		streak++
+		day = day.AddDate(0, 0, -1)
+	}
+	return streak
+}
+
+// streakText formats a streak for headers, or returns "" if there is none.
+func streakText(streak int) string {
+	if streak == 0 {
+		return ""
+	}
+	if streak == 1 {
+		return "🔥 1 day streak"
+	}
+	return fmt.Sprintf("🔥 %d day streak", streak)
+}
+
+// parseTags splits comma-separated input into tags, dropping empty entries
+// and repeats that differ only in case.
+func parseTags(input string) []string {
+	var tags []string
+	for _, tag := range strings.Split(input, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag != "" && !deck.ContainsFold(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// highlightMatches colors every case-insensitive occurrence of the needles
+// in text. Text whose lowercase form changes length is returned unchanged,
+// since match offsets wouldn't line up.
+func highlightMatches(text string, needles []string) string {
+	lower := strings.ToLower(text)
+	if len(lower) != len(text) {
+		return text
+	}
+	marked := make([]bool, len(text))
+	for _, needle := range needles {
+		needle = strings.ToLower(needle)
+		if needle == "" {
+			continue
+		}
+		for start := 0; ; {
+			i := strings.Index(lower[start:], needle)
+			if i < 0 {
+				break
+			}
+			for j := start + i; j < start+i+len(needle); j++ {
+				marked[j] = true
+			}
+			start += i + len(needle)
+		}
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(text); {
+		j := i
+		for j < len(text) && marked[j] == marked[i] {
+			j++
+		}
+		if marked[i] {
+			b.WriteString(pterm.Bold.Sprint(pterm.FgYellow.Sprint(text[i:j])))
+		} else {
+			b.WriteString(text[i:j])
+		}
+		i = j
+	}
+	return b.String()
+}
+
+// studyProfile is a named set of session settings, e.g. "morning review"
+// for due cards only, at most 20, or "exam cram" for a quiz over everything.
+type studyProfile struct {
+	Name     string `json:"name"`
+	Mode     string `json:"mode"`
+	Category string `json:"category,omitempty"`
+	DueOnly  bool   `json:"due_only,omitempty"`
+	Limit    int    `json:"limit,omitempty"`
+}
+
+// profilesPath is the sidecar file holding the deck's study profiles.
+func (app *FlashcardApp) profilesPath() string {
+	return app.FilePath + ".profiles"
+}
+
+func (app *FlashcardApp) loadProfiles() ([]studyProfile, error) {
+	data, err := ioutil.ReadFile(app.profilesPath())
+	if errors.Is(err, os.ErrNotExist) || (err == nil && len(data) == 0) {
+		return []studyProfile{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var profiles []studyProfile
+	if err := json.Unmarshal(data, &profiles); err != nil {
+		return nil, err
+	}
+	return profiles, nil
+}
+
+func (app *FlashcardApp) saveProfiles(profiles []studyProfile) error {
+	data, err := json.MarshalIndent(profiles, "", "  ")
+	if err != nil {
+		return err
+	}
+	if app.DryRun {
+		pterm.Info.Printf("Dry run: would write %d profiles to '%s'.\n", len(profiles), app.profilesPath())
+		return nil
+	}
+	return ioutil.WriteFile(app.profilesPath(), data, 0644)
+}
+
+func findProfile(profiles []studyProfile, name string) (studyProfile, bool) {
+	for _, profile := range profiles {
+		if strings.EqualFold(profile.Name, name) {
+			return profile, true
+		}
+	}
+	return studyProfile{}, false
+}
+
+// runProfile starts a session with the profile's settings, restoring the
+// previous settings afterwards. A review profile without DueOnly reviews
+// every card of its selection, due or not.
+func (app *FlashcardApp) runProfile(profile studyProfile) {
+	prevDueOnly, prevLimit := app.DueOnly, app.SessionLimit
+	defer func() {
+		app.DueOnly, app.SessionLimit, app.reviewAll = prevDueOnly, prevLimit, false
+	}()
+
+	pterm.Info.Printf("Using study profile '%s'.\n", profile.Name)
+	app.DueOnly = profile.DueOnly
+	if profile.Mode == "quiz" {
+		app.SessionLimit = 0
+		num := profile.Limit
+		if num <= 0 {
+			num = len(app.Flashcards)
+		}
+		app.quizMode(profile.Category, "", num)
+		return
+	}
+	app.SessionLimit = profile.Limit
+	app.reviewAll = !profile.DueOnly
+	app.reviewCards(profile.Category, "")
+}
+
+// manageProfiles lets the user run, create or delete study profiles.
+func (app *FlashcardApp) manageProfiles() {
+	const (
+		createProfile = "[Create new profile]"
+		deleteProfile = "[Delete a profile]"
+		back          = "Back"
+	)
+
+	profiles, err := app.loadProfiles()
+	if err != nil {
+		pterm.Error.Printf("Could not read study profiles from '%s': %v\n", app.profilesPath(), err)
+		return
+	}
+
+	options := []string{}
+	for _, profile := range profiles {
+		options = append(options, profile.Name)
+	}
+	options = append(options, createProfile)
+	if len(profiles) > 0 {
+		options = append(options, deleteProfile)
+	}
+	options = append(options, back)
+
+	selected, _ := pterm.DefaultInteractiveSelect.
+		WithOptions(options).
+		WithDefaultText("Select a study profile").
+		Show()
+
+	switch selected {
+	case "", back:
+		return
+	case createProfile:
+		profile, ok := app.promptProfile()
+		if !ok {
+			return
+		}
+		if _, exists := findProfile(profiles, profile.Name); exists {
+			pterm.Warning.Printf("A profile named '%s' already exists.\n", profile.Name)
+			return
+		}
+		if err := app.saveProfiles(append(profiles, profile)); err != nil {
+			pterm.Error.Printf("Could not save study profiles: %v\n", err)
+			return
+		}
+		pterm.Success.Printf("Saved study profile '%s'.\n", profile.Name)
+	case deleteProfile:
+		names := options[:len(profiles)]
+		name, _ := pterm.DefaultInteractiveSelect.
+			WithOptions(names).
+			WithDefaultText("Select the profile to delete").
+			Show()
+		remaining := []studyProfile{}
+		for _, profile := range profiles {
+			if profile.Name != name {
+				remaining = append(remaining, profile)
+			}
+		}
+		if err := app.saveProfiles(remaining); err != nil {
+			pterm.Error.Printf("Could not save study profiles: %v\n", err)
+			return
+		}
+		pterm.Success.Printf("Deleted study profile '%s'.\n", name)
+	default:
+		if profile, ok := findProfile(profiles, selected); ok {
+			app.runProfile(profile)
+		}
+	}
+}
+
+func (app *FlashcardApp) promptProfile() (studyProfile, bool) {
+	name, _ := pterm.DefaultInteractiveTextInput.Show("Profile name")
+	name = strings.TrimSpace(name)
+	if name == "" {
+		pterm.Warning.Println("Profile name cannot be empty.")
+		return studyProfile{}, false
+	}
+
+	mode, _ := pterm.DefaultInteractiveSelect.
+		WithOptions([]string{"review", "quiz"}).
+		WithDefaultText("Session type").
+		Show()
+	category := app.selectCategory("Category for this profile", true)
+	dueOnly, _ := pterm.DefaultInteractiveConfirm.
+		WithConfirmText("y").WithRejectText("n").
+		Show("Only cards that are due?")
+	limitStr, _ := pterm.DefaultInteractiveTextInput.
+		WithDefaultValue("0").
+		Show("Maximum number of cards (0 = no limit)")
+	limit, err := strconv.Atoi(strings.TrimSpace(limitStr))
+	if err != nil || limit < 0 {
+		pterm.Warning.Println("Invalid limit, using no limit.")
+		limit = 0
+	}
+
+	return studyProfile{Name: name, Mode: mode, Category: category, DueOnly: dueOnly, Limit: limit}, true
+}
+
+// masteredInterval is the scheduling interval in days from which a card
+// counts as mastered.
+const masteredInterval = 21
+
+// isMastered reports whether a card needs no more day-to-day attention:
+// scheduled cards (those with a next review date) once their interval
+// reaches masteredInterval or they sit in the last Leitner box, unscheduled
+// cards after at least five reviews with 90% accuracy or more.
+func isMastered(card Flashcard) bool {
+	if card.Interval >= masteredInterval || card.Box >= len(leitnerIntervals) {
+		return true
+	}
+	if card.NextReview != nil {
+		return false
+	}
+	return card.TimesReviewed >= 5 && float64(card.TimesCorrect) >= 0.9*float64(card.TimesReviewed)
+}
+
+// withoutMastered drops mastered cards unless HideMastered is off and
+// returns how many were hidden.
+func (app *FlashcardApp) withoutMastered(cards []Flashcard) ([]Flashcard, int) {
+	if !app.HideMastered {
+		return cards, 0
+	}
+	visible := []Flashcard{}
+	for _, card := range cards {
+		if !isMastered(card) {
+			visible = append(visible, card)
+		}
+	}
+	return visible, len(cards) - len(visible)
+}
+
+// onlyUnreviewed keeps the cards that were never reviewed.
+func onlyUnreviewed(cards []Flashcard) []Flashcard {
+	unreviewed := []Flashcard{}
+	for _, card := range cards {
+		if card.TimesReviewed == 0 || card.LastReviewed == nil {
+			unreviewed = append(unreviewed, card)
+		}
+	}
+	return unreviewed
+}
+
+// listUnreviewed shows the never-reviewed cards of a category (all when
+// empty) in the standard table.
+func (app *FlashcardApp) listUnreviewed(categoryFilter string) {
+	cards := deck.FilterByCategory(app.Flashcards, categoryFilter)
+	if app.TagFilter != "" {
+		cards = deck.FilterByTag(cards, app.TagFilter)
+	}
+	unreviewed := onlyUnreviewed(cards)
+	if len(unreviewed) == 0 {
+		pterm.Success.Printf("All %d cards have been reviewed at least once. Nothing slips through!\n", len(cards))
+		return
+	}
+	pterm.Info.Printf("%d of %d cards were never reviewed:\n", len(unreviewed), len(cards))
+	app.renderCardTable(unreviewed)
+}
+
+// listCardsToPick lists the cards before asking for the ID of one to edit,
+// delete or move. Mastered cards are shown too, as they can be picked.
+func (app *FlashcardApp) listCardsToPick() {
+	hideMastered := app.HideMastered
+	app.HideMastered = false
+	defer func() { app.HideMastered = hideMastered }()
+	pterm.Info.Println("Current cards:")
+	app.listCards("")
+}
+
+func (app *FlashcardApp) listCards(categoryFilter string) {
+	cards := deck.FilterByCategory(app.Flashcards, categoryFilter)
+	if app.TagFilter != "" {
+		cards = deck.FilterByTag(cards, app.TagFilter)
+	}
+	displayCards, hidden := app.withoutMastered(cards)
+	if hidden > 0 {
+		defer pterm.Info.Printf("+%d mastered hidden (use -all to show them).\n", hidden)
+	}
+
+	if len(displayCards) == 0 {
+		if categoryFilter != "" {
+			pterm.Warning.Printf("No cards found in category '%s' in '%s'.\n", categoryFilter, app.FilePath)
+		} else {
+			pterm.Warning.Printf("No flashcards available in '%s'.\n", app.FilePath)
+		}
+		return
+	}
+
+	sortCards(displayCards, app.SortBy)
+	if app.PageSize <= 0 || len(displayCards) <= app.PageSize || !isTerminal(os.Stdout) {
+		app.renderCardTable(displayCards)
+		return
+	}
+	pages := (len(displayCards) + app.PageSize - 1) / app.PageSize
+	for page := 0; page < pages; page++ {
+		end := (page + 1) * app.PageSize
+		if end > len(displayCards) {
+			end = len(displayCards)
+		}
+		app.renderCardTable(displayCards[page*app.PageSize : end])
+		pterm.Info.Printf("Page %d of %d (%d cards)\n", page+1, pages, len(displayCards))
+		if page == pages-1 {
+			break
+		}
+		next, _ := pterm.DefaultInteractiveContinue.
+			WithOptions([]string{"next", "quit"}).
+			Show("Show the next page?")
+		if next == "quit" {
+			break
+		}
+	}
+}
+
+// renderCardTable prints cards as the standard table, sorted per SortBy.
+func (app *FlashcardApp) renderCardTable(displayCards []Flashcard) {
+	app.renderHighlightedTable(displayCards, nil)
+}
+
+// renderHighlightedTable is renderCardTable with the given search terms
+// highlighted in the question column.
+func (app *FlashcardApp) renderHighlightedTable(displayCards []Flashcard, highlights []string) {
+	sortCards(displayCards, app.SortBy)
+
+	tableData := pterm.TableData{
+		{"ID", "Category", "Question", "📎", "Answer(s)", "Type", "Difficulty", "Reviewed", "Correct %", "Time"},
+	}
+
+	for _, card := range displayCards {
+		cardType := "Text"
+		if len(card.Options) > 0 {
+			cardType = "Multiple Choice"
+		} else if card.Essay {
+			cardType = "Essay"
+		}
+
+		answerText := card.Answer
+		if len(card.CorrectAnswers) > 1 {
+			answerText = fmt.Sprintf("%s (+%d more)", card.CorrectAnswers[0], len(card.CorrectAnswers)-1)
+		} else if len(card.CorrectAnswers) == 1 {
+			answerText = card.CorrectAnswers[0]
+		}
+
+		qShort := card.Question
+		if len(qShort) > 40 {
+			qShort = qShort[:37] + "..."
+		}
+		if len(highlights) > 0 {
+			qShort = highlightMatches(qShort, highlights)
+		}
+		aShort := answerText
+		if len(aShort) > 30 {
+			aShort = aShort[:27] + "..."
+		}
+		if app.HideAnswers {
+			aShort = "***"
+		}
+		catShort := card.Category
+		if len(catShort) > 15 {
+			catShort = catShort[:12] + "..."
+		}
+
+		reviewedCount := fmt.Sprintf("%d", card.TimesReviewed)
+		correctPercent := "N/A"
+		if card.TimesReviewed > 0 {
+			percent := (float64(card.TimesCorrect) / float64(card.TimesReviewed)) * 100
+			correctPercent = fmt.Sprintf("%.0f%%", percent)
+		}
+
+		attached := ""
+		if card.Attachment != "" {
+			attached = "📎"
+		}
+
+		tableData = append(tableData, []string{
+			strconv.Itoa(card.ID),
+			catShort,
+			qShort,
+			attached,
+			aShort,
+			cardType,
+			card.Difficulty,
+			reviewedCount,
+			correctPercent,
+			formatMillis(card.TotalTimeMs),
+		})
+	}
+
+	pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+}
+
+// listFormats are the output formats of printCards.
+var listFormats = []string{"table", "json", "csv"}
+
+// checkListFormat rejects unknown -format values before anything is loaded
+// or printed.
+func checkListFormat(format string) error {
+	for _, known := range listFormats {
+		if format == known {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown list format '%s' (use %s)", format, strings.Join(listFormats, ", "))
+}
+
+// printCards writes cards to stdout in the given format. "json" emits the
+// full card objects and "csv" a column subset; "table" renders the regular
+// table.
+func (app *FlashcardApp) printCards(cards []Flashcard, format string) error {
+	sortCards(cards, app.SortBy)
+	switch format {
+	case "json":
+		data, err := json.MarshalIndent(cards, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(os.Stdout, string(data))
+		return err
+	case "csv":
+		w := csv.NewWriter(os.Stdout)
+		_ = w.Write([]string{"id", "category", "question", "answer", "correct_answers", "options", "times_reviewed", "times_correct", "total_time_ms"})
+		for _, card := range cards {
+			_ = w.Write([]string{
+				strconv.Itoa(card.ID),
+				card.Category,
+				card.Question,
+				card.Answer,
+				strings.Join(card.CorrectAnswers, "|"),
+				strings.Join(card.Options, "|"),
+				strconv.Itoa(card.TimesReviewed),
+				strconv.Itoa(card.TimesCorrect),
+				strconv.FormatInt(card.TotalTimeMs, 10),
+			})
+		}
+		w.Flush()
+		return w.Error()
+	case "", "table":
+		if len(cards) == 0 {
+			pterm.Warning.Println("No matching cards.")
+			return nil
+		}
+		app.renderCardTable(cards)
+		return nil
+	default:
+		return fmt.Errorf("unknown list format '%s' (use table, json or csv)", format)
+	}
+}
+
+// sortCards orders cards in place. "time" puts the cards with the most
+// accumulated study time first; anything else sorts by ID.
+func sortCards(cards []Flashcard, sortBy string) {
+	sort.SliceStable(cards, func(i, j int) bool {
+		if sortBy == "time" && cards[i].TotalTimeMs != cards[j].TotalTimeMs {
+			return cards[i].TotalTimeMs > cards[j].TotalTimeMs
+		}
+		return cards[i].ID < cards[j].ID
+	})
+}
+
+func formatMillis(ms int64) string {
+	if ms <= 0 {
+		return "-"
+	}
+	return (time.Duration(ms) * time.Millisecond).Round(time.Second).String()
+}
+
+// browseTags lists all tags with their card counts and shows the cards of
+// the one picked.
+func (app *FlashcardApp) browseTags() {
+	tags := app.Tags()
+	if len(tags) == 0 {
+		pterm.Warning.Println("No cards are tagged yet. Add tags when adding or editing a card.")
+		return
+	}
+
+	tableData := pterm.TableData{{"Tag", "Cards"}}
+	options := make([]string, 0, len(tags)+1)
+	for _, tag := range tags {
+		tableData = append(tableData, []string{tag.Tag, strconv.Itoa(tag.Cards)})
+		options = append(options, tag.Tag)
+	}
+	pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+
+	const back = "[Back]"
+	selected, _ := pterm.DefaultInteractiveSelect.
+		WithOptions(append(options, back)).
+		WithDefaultText("Show the cards with tag").
+		Show()
+	if selected == "" || selected == back {
+		return
+	}
+	app.renderCardTable(deck.FilterByTag(app.Flashcards, selected))
+}
+
+// weakestCards returns up to n cards with at least 3 reviews, lowest
+// accuracy first, and the number of mastered cards left out per
+// HideMastered.
+func (app *FlashcardApp) weakestCards(n int) ([]Flashcard, int) {
+	candidates := []Flashcard{}
+	for _, card := range app.Flashcards {
+		if card.TimesReviewed >= 3 {
+			candidates = append(candidates, card)
+		}
+	}
+	candidates, hidden := app.withoutMastered(candidates)
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a := float64(candidates[i].TimesCorrect) / float64(candidates[i].TimesReviewed)
+		b := float64(candidates[j].TimesCorrect) / float64(candidates[j].TimesReviewed)
+		if a != b {
+			return a < b
+		}
+		return candidates[i].ID < candidates[j].ID
+	})
+	if len(candidates) > n {
+		candidates = candidates[:n]
+	}
+	return candidates, hidden
+}
+
+// showStats prints a deck overview: card and category counts, overall
+// accuracy and the five weakest cards with at least 3 reviews. Mastered
+// cards are left out of the weakest list unless HideMastered is off.
+func (app *FlashcardApp) showStats() {
+	pterm.DefaultSection.Printf("Statistics for '%s'", app.FilePath)
+	if len(app.Flashcards) == 0 {
+		pterm.Warning.Println("No flashcards yet. Add some to see statistics.")
+		return
+	}
+
+	totals := app.Totals()
+	overall := "N/A"
+	if totals.Reviewed > 0 {
+		overall = fmt.Sprintf("%.0f%% (%d/%d)", float64(totals.Correct)/float64(totals.Reviewed)*100, totals.Correct, totals.Reviewed)
+	}
+	streak := streakText(app.currentStreak())
+	if streak == "" {
+		streak = "No study streak yet"
+	}
+	pterm.DefaultBulletList.WithItems([]pterm.BulletListItem{
+		{Level: 0, Text: fmt.Sprintf("Total cards: %d", totals.Cards)},
+		{Level: 0, Text: fmt.Sprintf("Overall accuracy: %s", overall)},
+		{Level: 0, Text: fmt.Sprintf("Never reviewed: %d", totals.NeverReviewed)},
+		{Level: 0, Text: fmt.Sprintf("Hints used: %d", totals.Hinted)},
+		{Level: 0, Text: streak},
+	}).Render()
+
+	tableData := pterm.TableData{{"Category", "Cards", "Reviews", "Correct %"}}
+	for _, stat := range app.CategoryStats() {
+		accuracy := "N/A"
+		if stat.Reviewed > 0 {
+			accuracy = fmt.Sprintf("%.0f%%", stat.Accuracy())
+		}
+		tableData = append(tableData, []string{stat.Category, strconv.Itoa(stat.Cards), strconv.Itoa(stat.Reviewed), accuracy})
+	}
+	pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+
+	app.showSlowestCards()
+
+	candidates, hidden := app.weakestCards(5)
+	if len(candidates) == 0 {
+		pterm.Info.Println("No cards with at least 3 reviews to rank yet.")
+		return
+	}
+
+	pterm.DefaultSection.WithLevel(2).Println("Weakest cards")
+	items := []pterm.BulletListItem{}
+	for _, card := range candidates {
+		text := fmt.Sprintf("[%d] %s - %.0f%% (%d/%d)",
+			card.ID, card.Question, float64(card.TimesCorrect)/float64(card.TimesReviewed)*100, card.TimesCorrect, card.TimesReviewed)
+		if card.TimesHinted > 0 {
+			text += fmt.Sprintf(", %d with hints", card.TimesHinted)
+		}
+		items = append(items, pterm.BulletListItem{Level: 0, Text: text})
+	}
+	pterm.DefaultBulletList.WithItems(items).Render()
+	if hidden > 0 {
+		pterm.Info.Printf("+%d mastered hidden (use -all to show them).\n", hidden)
+	}
+}
+
+// averageAnswerTime returns how long the card's timed answers in reviews,
+// quizzes and cram sessions took on average, and false when none was timed
+// yet.
+func averageAnswerTime(card Flashcard) (time.Duration, bool) {
+	if card.TimesAnswered == 0 {
+		return 0, false
+	}
+	return time.Duration(card.TotalTimeMs/int64(card.TimesAnswered)) * time.Millisecond, true
+}
+
+// showSlowestCards lists the five cards with the longest average answer
+// time.
+func (app *FlashcardApp) showSlowestCards() {
+	answered := []Flashcard{}
+	for _, card := range app.Flashcards {
+		if _, ok := averageAnswerTime(card); ok {
+			answered = append(answered, card)
+		}
+	}
+	if len(answered) == 0 {
+		return
+	}
+	sort.SliceStable(answered, func(i, j int) bool {
+		a, _ := averageAnswerTime(answered[i])
+		b, _ := averageAnswerTime(answered[j])
+		if a != b {
+			return a > b
+		}
+		return answered[i].ID < answered[j].ID
+	})
+	if len(answered) > 5 {
+		answered = answered[:5]
+	}
+
+	pterm.DefaultSection.WithLevel(2).Println("Slowest cards")
+	items := []pterm.BulletListItem{}
+	for _, card := range answered {
+		average, _ := averageAnswerTime(card)
+		items = append(items, pterm.BulletListItem{Level: 0, Text: fmt.Sprintf("[%d] %s - %s on average (%d answers)",
+			card.ID, card.Question, average.Round(100*time.Millisecond), card.TimesAnswered)})
+	}
+	pterm.DefaultBulletList.WithItems(items).Render()
+}
+
+// autoWeakest is the quiz category choice that picks weakestCategory.
+const autoWeakest = "[Auto: my weakest category]"
+
+// minWeakestReviews is how many reviews a category needs before
+// weakestCategory considers it.
+const minWeakestReviews = 5
+
+// weakestCategory returns the category with the lowest share of correct
+// reviews among those with at least minWeakestReviews reviews, and false
+// when no category has enough reviews yet.
+func (app *FlashcardApp) weakestCategory() (string, bool) {
+	weakest, found := deck.CategoryStat{}, false
+	for _, stat := range app.CategoryStats() {
+		if stat.Reviewed < minWeakestReviews {
+			continue
+		}
+		if !found || stat.Accuracy() < weakest.Accuracy() {
+			weakest, found = stat, true
+		}
+	}
+	return weakest.Category, found
+}
+
+// startupSummary prints one line on what needs attention: cards not
+// reviewed for over a week (never-reviewed cards count once they are a week
+// old) and reviewed categories below 50% accuracy.
+func (app *FlashcardApp) startupSummary() {
+	if len(app.Flashcards) == 0 {
+		return
+	}
+	weekAgo := time.Now().AddDate(0, 0, -7)
+	stale := 0
+	for _, card := range app.Flashcards {
+		last := card.CreatedAt
+		if card.LastReviewed != nil {
+			last = *card.LastReviewed
+		}
+		if last.Before(weekAgo) {
+			stale++
+		}
+	}
+	weak := 0
+	for _, stat := range app.CategoryStats() {
+		if stat.Reviewed > 0 && stat.Accuracy() < 50 {
+			weak++
+		}
+	}
+
+	if stale == 0 && weak == 0 {
+		pterm.Success.Println("You're all caught up: every card was reviewed this week and no category is below 50%. Keep it up!")
+		return
+	}
+	parts := []string{}
+	if stale > 0 {
+		parts = append(parts, fmt.Sprintf("%d cards not reviewed in over a week", stale))
+	}
+	if weak > 0 {
+		parts = append(parts, fmt.Sprintf("%d categories with below-50%% accuracy", weak))
+	}
+	pterm.Info.Printf("You have %s.\n", strings.Join(parts, " and "))
+}
+
+// weakCategoryAlert points out reviewed categories whose accuracy is below
+// threshold and offers to start a review of the weakest one right away.
+func (app *FlashcardApp) weakCategoryAlert(threshold float64) {
+	weak := []deck.CategoryStat{}
+	for _, stat := range app.CategoryStats() {
+		if stat.Reviewed > 0 && stat.Accuracy() < threshold {
+			weak = append(weak, stat)
+		}
+	}
+	if len(weak) == 0 {
+		return
+	}
+	sort.SliceStable(weak, func(i, j int) bool {
+		return weak[i].Accuracy() < weak[j].Accuracy()
+	})
+
+	for _, stat := range weak[1:] {
+		pterm.Warning.Printf("%s is at %.0f%%.\n", stat.Category, stat.Accuracy())
+	}
+	weakest := weak[0]
+	reviewNow, _ := pterm.DefaultInteractiveConfirm.
+		WithDefaultValue(false).
+		WithConfirmText("y").
+		WithRejectText("n").
+		Show(fmt.Sprintf("%s is at %.0f%% — want to review it now?", weakest.Category, weakest.Accuracy()))
+	if reviewNow {
+		app.reviewCards(weakest.Category, "")
+		fmt.Println()
+	}
+}
+
+// deleteCard is the interactive side of removeCard: it shows the card and
+// asks first unless Force is set, then reports the result.
+func (app *FlashcardApp) deleteCard(cardID int) bool {
+	index, found := app.CardIndex(cardID)
+	if !found {
+		pterm.Error.Printf("Card with ID %d not found in '%s'.\n", cardID, app.FilePath)
+		return false
+	}
+
+	if !app.Force {
+		printCardDetails(app.Flashcards[index])
+		sure, _ := pterm.DefaultInteractiveConfirm.
+			WithDefaultValue(false).
+			WithConfirmText("y").WithRejectText("n").
+			Show("Are you sure you want to delete this card?")
+		if !sure {
+			pterm.Info.Println("Card kept.")
+			return false
+		}
+	}
+
+	deleted, err := app.removeCard(cardID)
+	if err != nil {
+		return false
+	}
+	pterm.Success.Printf("Moved card (ID: %d) in '%s' to the trash: %s\n", cardID, app.FilePath, deleted.Question)
+	return true
+}
+
+// removeCard moves the card with the given ID to the trash and saves the
+// deck, without asking or printing anything but save errors. It returns the
+// removed card.
+func (app *FlashcardApp) removeCard(cardID int) (Flashcard, error) {
+	deleted, err := app.Remove(cardID)
+	if err != nil {
+		return deleted, err
+	}
+	return deleted, app.saveFlashcards()
+}
+
+// deleteByCategory moves all cards of a category (ignoring case) to the
+// trash after one confirmation and returns how many were moved.
+func (app *FlashcardApp) deleteByCategory(category string) int {
+	return app.deleteMatching(fmt.Sprintf("in category '%s'", category), func(card Flashcard) bool {
+		return strings.EqualFold(card.Category, category)
+	})
+}
+
+// deleteByIDRange moves the cards with IDs from..to (inclusive) to the trash
+// after one confirmation and returns how many were moved.
+func (app *FlashcardApp) deleteByIDRange(from, to int) int {
+	return app.deleteMatching(fmt.Sprintf("with IDs %d to %d", from, to), func(card Flashcard) bool {
+		return card.ID >= from && card.ID <= to
+	})
+}
+
+// deleteMatching moves every card for which match is true to the trash with a
+// single save. what describes the selection in the confirmation and messages.
+func (app *FlashcardApp) deleteMatching(what string, match func(Flashcard) bool) int {
+	count := 0
+	for _, card := range app.Flashcards {
+		if match(card) {
+			count++
+		}
+	}
+	if count == 0 {
+		pterm.Warning.Printf("No cards %s in '%s'.\n", what, app.FilePath)
+		return 0
+	}
+	if !app.Force {
+		sure, _ := pterm.DefaultInteractiveConfirm.
+			WithDefaultValue(false).
+			WithConfirmText("y").WithRejectText("n").
+			Show(fmt.Sprintf("Move %d cards %s to the trash?", count, what))
+		if !sure {
+			pterm.Info.Println("Cards kept.")
+			return 0
+		}
+	}
+
+	now := time.Now()
+	kept := app.Flashcards[:0]
+	deleted := 0
+	for _, card := range app.Flashcards {
+		if !match(card) {
+			kept = append(kept, card)
+			continue
+		}
+		card.DeletedAt = &now
+		app.Trash = append(app.Trash, card)
+		deleted++
+	}
+	app.Flashcards = kept
+	if err := app.saveFlashcards(); err != nil {
+		return 0
+	}
+	pterm.Success.Printf("Moved %d cards %s in '%s' to the trash.\n", deleted, what, app.FilePath)
+	return deleted
+}
+
+// parseIDRange parses "from-to", e.g. "10-25", or a single ID.
+func parseIDRange(value string) (from, to int, err error) {
+	first, last, isRange := strings.Cut(strings.TrimSpace(value), "-")
+	if from, err = strconv.Atoi(strings.TrimSpace(first)); err != nil {
+		return 0, 0, fmt.Errorf("invalid ID range '%s' (use from-to, e.g. 10-25)", value)
+	}
+	to = from
+	if isRange {
+		if to, err = strconv.Atoi(strings.TrimSpace(last)); err != nil {
+			return 0, 0, fmt.Errorf("invalid ID range '%s' (use from-to, e.g. 10-25)", value)
+		}
+	}
+	if from > to {
+		return 0, 0, fmt.Errorf("invalid ID range '%s': %d is greater than %d", value, from, to)
+	}
+	return from, to, nil
+}
+
+// printAttachment shows the card's attachment, if any, so it can be opened
+// in another window. Local files get their size, or a warning when missing.
+func printAttachment(card Flashcard) {
+	if card.Attachment == "" {
+		return
+	}
+	text := "📎 Attachment: " + card.Attachment
+	if !strings.Contains(card.Attachment, "://") {
+		if info, err := os.Stat(card.Attachment); err == nil {
+			text += fmt.Sprintf(" (%s)", formatSize(info.Size()))
+		} else {
+			text += " (file not found)"
+		}
+	}
+	pterm.FgMagenta.Println(text)
+}
+
+// formatSize renders a byte count as B, KB or MB.
+func formatSize(bytes int64) string {
+	switch {
+	case bytes < 1024:
+		return fmt.Sprintf("%d B", bytes)
+	case bytes < 1024*1024:
+		return fmt.Sprintf("%.1f KB", float64(bytes)/1024)
+	default:
+		return fmt.Sprintf("%.1f MB", float64(bytes)/(1024*1024))
+	}
+}
+
+// printCardDetails shows everything about a card in a box: question,
+// answers, options, category and stats.
+func printCardDetails(card Flashcard) {
+	lines := []string{
+		pterm.Bold.Sprint("Question: ") + card.Question,
+	}
+	answers := card.CorrectAnswers
+	if len(answers) == 0 {
+		answers = []string{card.Answer}
+	}
+	lines = append(lines, pterm.Bold.Sprint("Answer(s): ")+strings.Join(answers, ", "))
+	if len(card.Options) > 0 {
+		options := strings.Join(card.Options, ", ")
+		if isSelectAll(card) {
+			options += " (select all that apply)"
+		}
+		lines = append(lines, pterm.Bold.Sprint("Options: ")+options)
+	}
+	lines = append(lines,
+		pterm.Bold.Sprint("Category: ")+card.Category,
+	)
+	if len(card.Tags) > 0 {
+		lines = append(lines, pterm.Bold.Sprint("Tags: ")+strings.Join(card.Tags, ", "))
+	}
+	if card.Note != "" {
+		lines = append(lines, pterm.Bold.Sprint("Note: ")+card.Note)
+	}
+	if card.Attachment != "" {
+		lines = append(lines, pterm.Bold.Sprint("Attachment: ")+card.Attachment)
+	}
+	scheduler := card.Scheduler
+	if scheduler == "" {
+		scheduler = "deck default"
+	}
+	lines = append(lines,
+		pterm.Bold.Sprint("Difficulty: ")+card.Difficulty,
+		pterm.Bold.Sprint("Scheduler: ")+scheduler,
+		pterm.Bold.Sprint("Created: ")+card.CreatedAt.Format("2006-01-02 15:04"),
+	)
+
+	stats := fmt.Sprintf("reviewed %d times, %d correct", card.TimesReviewed, card.TimesCorrect)
+	if card.TimesReviewed > 0 {
+		stats += fmt.Sprintf(" (%.0f%%)", float64(card.TimesCorrect)/float64(card.TimesReviewed)*100)
+	}
+	if card.LastReviewed != nil {
+		stats += ", last on " + card.LastReviewed.Format("2006-01-02")
+	}
+	lines = append(lines, pterm.Bold.Sprint("Stats: ")+stats)
+
+	pterm.DefaultBox.WithTitle(fmt.Sprintf("Card %d", card.ID)).Println(strings.Join(lines, "\n"))
+}
+
+// showCard prints the full, untruncated details of one card.
+func (app *FlashcardApp) showCard(id int) bool {
+	index, found := app.CardIndex(id)
+	if !found {
+		pterm.Error.Printf("Card with ID %d not found in '%s'.\n", id, app.FilePath)
+		return false
+	}
+	printCardDetails(app.Flashcards[index])
+	return true
+}
+
+// restoreFromTrash puts the trashed card with the given (old) ID back into
+// the deck under a fresh ID.
+func (app *FlashcardApp) restoreFromTrash(cardID int) bool {
+	for i, card := range app.Trash {
+		if card.ID != cardID {
+			continue
+		}
+		card.ID = app.NextID()
+		card.DeletedAt = nil
+		app.Flashcards = append(app.Flashcards, card)
+		app.Trash = append(app.Trash[:i], app.Trash[i+1:]...)
+		if err := app.saveFlashcards(); err != nil {
+			return false
+		}
+		pterm.Success.Printf("Restored card as ID %d in '%s': %s\n", card.ID, app.FilePath, card.Question)
+		return true
+	}
+	pterm.Error.Printf("No card with ID %d in the trash of '%s'.\n", cardID, app.FilePath)
+	return false
+}
+
+// manageTrash shows the deleted cards and offers to restore one or to empty
+// the trash for good.
+func (app *FlashcardApp) manageTrash() {
+	const (
+		restore = "Restore a card"
+		empty   = "Empty trash"
+		back    = "Back"
+	)
+	if len(app.Trash) == 0 {
+		pterm.Info.Println("The trash is empty.")
+		return
+	}
+	pterm.Info.Printf("%d cards in the trash (IDs are the ones they had before deletion):\n", len(app.Trash))
+	app.renderCardTable(append([]Flashcard(nil), app.Trash...))
+
+	selected, _ := pterm.DefaultInteractiveSelect.
+		WithOptions([]string{restore, empty, back}).
+		WithDefaultText("What do you want to do?").
+		Show()
+	switch selected {
+	case restore:
+		idStr, _ := pterm.DefaultInteractiveTextInput.Show("Enter ID of card to restore")
+		id, err := strconv.Atoi(strings.TrimSpace(idStr))
+		if err != nil {
+			pterm.Error.Println("Invalid ID entered.")
+			return
+		}
+		app.restoreFromTrash(id)
+	case empty:
+		confirm, _ := pterm.DefaultInteractiveConfirm.
+			WithDefaultValue(false).
+			WithConfirmText("y").WithRejectText("n").
+			Show(fmt.Sprintf("Permanently delete the %d cards in the trash?", len(app.Trash)))
+		if !confirm {
+			return
+		}
+		count := len(app.Trash)
+		app.Trash = nil
+		if err := app.saveFlashcards(); err == nil {
+			pterm.Success.Printf("Permanently deleted %d cards.\n", count)
+		}
+	}
+}
+
+// promptKeep asks for a new value, keeping current when the input is blank.
+func promptKeep(label, current string) string {
+	value, _ := pterm.DefaultInteractiveTextInput.
+		Show(fmt.Sprintf("%s (blank keeps '%s')", label, current))
+	if strings.TrimSpace(value) == "" {
+		return current
+	}
+	return strings.TrimSpace(value)
+}
+
+// editOptions asks for the options of a multiple-choice card one by one,
+// then for new ones, and which of them are correct. It returns the options,
+// the correct ones and the option notes kept for the options left.
+func editOptions(card Flashcard) ([]string, []string, map[string]string) {
+	options := []string{}
+	notes := map[string]string{}
+	for i, option := range card.Options {
+		value, _ := pterm.DefaultInteractiveTextInput.
+			Show(fmt.Sprintf("Option %d (blank keeps '%s', '-' removes it)", i+1, option))
+		value = strings.TrimSpace(value)
+		switch value {
+		case "-":
+			continue
+		case "":
+			value = option
+		}
+		options = append(options, value)
+		if note, ok := card.OptionNotes[option]; ok {
+			notes[value] = note
+		}
+	}
+	for {
+		value, _ := pterm.DefaultInteractiveTextInput.
+			Show(fmt.Sprintf("New option %d (blank to finish)", len(options)+1))
+		value = strings.TrimSpace(value)
+		if value == "" {
+			if len(options) < 2 {
+				pterm.Warning.Println("Need at least 2 options for multiple choice. Please add more.")
+				continue
+			}
+			break
+		}
+		options = append(options, value)
+	}
+
+	correct := []string{}
+	for _, option := range options {
+		wasCorrect := false
+		for _, answer := range card.CorrectAnswers {
+			if strings.EqualFold(answer, option) {
+				wasCorrect = true
+				break
+			}
+		}
+		isCorrect, _ := pterm.DefaultInteractiveConfirm.
+			WithDefaultValue(wasCorrect).
+			WithConfirmText("y").WithRejectText("n").
+			Show(fmt.Sprintf("Is '%s' a correct answer?", option))
+		if isCorrect {
+			correct = append(correct, option)
+		}
+	}
+	if len(correct) == 0 {
+		correct = []string{options[0]}
+		pterm.Warning.Printf("No correct answer specified for multiple choice. Defaulting to first option: '%s'\n", options[0])
+	}
+	return options, correct, notes
+}
+
+// editCard interactively changes a card's question, answer, category and,
+// for multiple-choice cards, its options and correct answers. Blank input
+// keeps the current value; statistics and dates are left untouched.
+func (app *FlashcardApp) editCard(cardID int) bool {
+	index, found := app.CardIndex(cardID)
+	if !found {
+		pterm.Error.Printf("Card with ID %d not found in '%s'.\n", cardID, app.FilePath)
+		return false
+	}
+	card := app.Flashcards[index]
+
+	card.Question = promptKeep("Question", card.Question)
+	oldAnswer := card.Answer
+	card.Answer = promptKeep("Main answer", card.Answer)
+	card.Category = promptKeep("Category", card.Category)
+	if tags := promptKeep("Tags, comma-separated ('-' removes all)", strings.Join(card.Tags, ", ")); tags == "-" {
+		card.Tags = nil
+	} else {
+		card.Tags = parseTags(tags)
+	}
+	card.Difficulty = selectDifficulty("Difficulty", card.Difficulty)
+	card.Scheduler = selectScheduler("Scheduler", card.Scheduler)
+	if note := promptKeep("Note ('-' removes it)", card.Note); note == "-" {
+		card.Note = ""
+	} else {
+		card.Note = note
+	}
+	if attachment := promptKeep("Attachment path or URL ('-' removes it)", card.Attachment); attachment == "-" {
+		card.Attachment = ""
+	} else {
+		card.Attachment = attachment
+	}
+
+	if len(card.Options) > 0 {
+		options, correct, notes := editOptions(card)
+		for {
+			err := validateMCOptions(options, correct)
+			if err == nil {
+				break
+			}
+			pterm.Error.Printf("%v. Please enter the options again.\n", err)
+			options, correct, notes = editOptions(card)
+		}
+		card.Options = options
+		card.CorrectAnswers = correct
+		card.OptionNotes = nil
+		if len(notes) > 0 {
+			card.OptionNotes = notes
+		}
+		if !deck.ContainsFold(correct, card.PrimaryAnswer) {
+			card.PrimaryAnswer = ""
+		}
+		card.MultiSelect = false
+		if len(correct) > 1 {
+			card.MultiSelect, _ = pterm.DefaultInteractiveConfirm.
+				WithDefaultValue(isSelectAll(app.Flashcards[index])).
+				WithConfirmText("y").WithRejectText("n").
+				Show("Must all correct options be selected ('select all that apply')?")
+		}
+	} else {
+		card.Essay, _ = pterm.DefaultInteractiveConfirm.
+			WithDefaultValue(card.Essay).
+			WithConfirmText("y").WithRejectText("n").
+			Show("Is this an open question you grade yourself?")
+		if card.Answer != oldAnswer {
+			card.CorrectAnswers = replaceAnswer(card.CorrectAnswers, oldAnswer, card.Answer)
+			if card.PrimaryAnswer == oldAnswer {
+				card.PrimaryAnswer = card.Answer
+			}
+		}
+	}
+
+	app.Flashcards[index] = card
+	if err := app.saveFlashcards(); err != nil {
+		return false
+	}
+	pterm.Success.Printf("Updated card (ID: %d) in '%s': %s\n", card.ID, app.FilePath, card.Question)
+	return true
+}
+
+// replaceAnswer swaps the old main answer for the new one in a card's
+// accepted answers, keeping the alternatives. When the old answer isn't
+// among them, the new one is put first.
+func replaceAnswer(answers []string, oldAnswer, newAnswer string) []string {
+	replaced := []string{}
+	found := false
+	for _, answer := range answers {
+		if strings.EqualFold(answer, oldAnswer) && !found {
+			answer, found = newAnswer, true
+		}
+		if !deck.ContainsFold(replaced, answer) {
+			replaced = append(replaced, answer)
+		}
+	}
+	if !found && !deck.ContainsFold(replaced, newAnswer) {
+		replaced = append([]string{newAnswer}, replaced...)
+	}
+	return replaced
+}
+
+// editCorrectAnswers changes only which options of a multiple-choice card
+// are correct. The options are listed with their current state, then
+// toggled in a multi-select prompt.
+func (app *FlashcardApp) editCorrectAnswers(cardID int) bool {
+	index, found := app.CardIndex(cardID)
+	if !found {
+		pterm.Error.Printf("Card with ID %d not found in '%s'.\n", cardID, app.FilePath)
+		return false
+	}
+	card := app.Flashcards[index]
+	if len(card.Options) == 0 {
+		pterm.Error.Printf("Card %d is not a multiple-choice card.\n", cardID)
+		return false
+	}
+
+	pterm.FgLightBlue.Println(card.Question)
+	preselected := []string{}
+	for _, option := range card.Options {
+		if deck.ContainsFold(card.CorrectAnswers, option) {
+			pterm.FgGreen.Println("  ✓", option)
+			preselected = append(preselected, option)
+		} else {
+			pterm.FgGray.Println("  ✗", option)
+		}
+	}
+
+	correct, _ := pterm.DefaultInteractiveMultiselect.
+		WithOptions(card.Options).
+		WithDefaultOptions(preselected).
+		WithMaxHeight(len(card.Options)).
+		WithDefaultText("Correct options (Enter toggles, Tab saves)").
+		Show()
+	if err := validateMCOptions(card.Options, correct); err != nil {
+		pterm.Error.Printf("Card not changed: %v.\n", err)
+		return false
+	}
+
+	card.CorrectAnswers = correct
+	if !deck.ContainsFold(correct, card.PrimaryAnswer) {
+		card.PrimaryAnswer = ""
+	}
+	app.Flashcards[index] = card
+	if err := app.saveFlashcards(); err != nil {
+		return false
+	}
+	pterm.Success.Printf("Card %d now accepts: %s\n", card.ID, strings.Join(correct, ", "))
+	return true
+}
+
+// renameCategory moves every card in category oldName (ignoring case) to
+// newName, which merges the two when newName is already in use. It saves
+// once and returns the number of cards changed.
+func (app *FlashcardApp) renameCategory(oldName, newName string) (int, error) {
+	newName = strings.TrimSpace(newName)
+	if newName == "" {
+		return 0, errors.New("the new category name must not be empty")
+	}
+	renamed := 0
+	for i, card := range app.Flashcards {
+		if strings.EqualFold(card.Category, oldName) && card.Category != newName {
+			app.Flashcards[i].Category = newName
+			renamed++
+		}
+	}
+	if renamed == 0 {
+		return 0, nil
+	}
+	if err := app.saveFlashcards(); err != nil {
+		return 0, err
+	}
+	return renamed, nil
+}
+
+// findReplace replaces every case-sensitive occurrence of find in the
+// questions and/or answers (main answer, correct answers and options) of all
+// cards. The affected cards are shown first and, unless Force is set, the
+// user confirms. Cards whose options would become invalid are skipped. It
+// returns the number of replacements and of cards changed.
+func (app *FlashcardApp) findReplace(find, replace string, fieldsQuestion, fieldsAnswer bool) (int, int, error) {
+	if find == "" {
+		return 0, 0, errors.New("the text to find must not be empty")
+	}
+	if !fieldsQuestion && !fieldsAnswer {
+		return 0, 0, errors.New("no fields selected")
+	}
+
+	affected := []Flashcard{}
+	occurrences := 0
+	for _, card := range app.Flashcards {
+		if _, count := replaceInCard(card, find, replace, fieldsQuestion, fieldsAnswer); count > 0 {
+			affected = append(affected, card)
+			occurrences += count
+		}
+	}
+	if len(affected) == 0 {
+		pterm.Info.Printf("No card contains '%s'.\n", find)
+		return 0, 0, nil
+	}
+	pterm.Info.Printf("'%s' occurs %d times in %d cards:\n", find, occurrences, len(affected))
+	app.renderHighlightedTable(affected, []string{find})
+	if !app.Force {
+		sure, _ := pterm.DefaultInteractiveConfirm.
+			WithDefaultValue(false).
+			WithConfirmText("y").WithRejectText("n").
+			Show(fmt.Sprintf("Replace all of them with '%s'?", replace))
+		if !sure {
+			pterm.Info.Println("Nothing replaced.")
+			return 0, 0, nil
+		}
+	}
+
+	replacements, changed := 0, 0
+	for i, card := range app.Flashcards {
+		updated, count := replaceInCard(card, find, replace, fieldsQuestion, fieldsAnswer)
+		if count == 0 {
+			continue
+		}
+		if len(updated.Options) > 0 {
+			if err := validateMCOptions(updated.Options, updated.CorrectAnswers); err != nil {
+				pterm.Warning.Printf("Skipped card %d: %v.\n", card.ID, err)
+				continue
+			}
+		}
+		app.Flashcards[i] = updated
+		replacements += count
+		changed++
+	}
+	if changed == 0 {
+		return 0, 0, nil
+	}
+	if err := app.saveFlashcards(); err != nil {
+		return 0, 0, err
+	}
+	return replacements, changed, nil
+}
+
+// replaceInCard returns a copy of card with find replaced in the selected
+// fields and the number of occurrences replaced. The answer fields repeat
+// each other (correct answers are also options, the main answer is usually
+// a correct answer), so every distinct answer text is counted once.
+func replaceInCard(card Flashcard, find, replace string, fieldsQuestion, fieldsAnswer bool) (Flashcard, int) {
+	count := 0
+	if fieldsQuestion {
+		count += strings.Count(card.Question, find)
+		card.Question = strings.ReplaceAll(card.Question, find, replace)
+	}
+	if !fieldsAnswer {
+		return card, count
+	}
+
+	answers := card.Options
+	if len(answers) == 0 {
+		answers = card.CorrectAnswers
+	}
+	for _, answer := range answers {
+		count += strings.Count(answer, find)
+	}
+	if !slices.Contains(answers, card.Answer) {
+		count += strings.Count(card.Answer, find)
+	}
+
+	replaceAll := func(list []string) []string {
+		if list == nil {
+			return nil
+		}
+		replaced := make([]string, len(list))
+		for i, text := range list {
+			replaced[i] = strings.ReplaceAll(text, find, replace)
+		}
+		return replaced
+	}
+	card.Answer = strings.ReplaceAll(card.Answer, find, replace)
+	card.CorrectAnswers = replaceAll(card.CorrectAnswers)
+	card.Options = replaceAll(card.Options)
+	card.PrimaryAnswer = strings.ReplaceAll(card.PrimaryAnswer, find, replace)
+	if len(card.OptionNotes) > 0 {
+		notes := make(map[string]string, len(card.OptionNotes))
+		for option, note := range card.OptionNotes {
+			notes[strings.ReplaceAll(option, find, replace)] = note
+		}
+		card.OptionNotes = notes
+	}
+	return card, count
+}
+
+// moveCard appends a card, including its statistics, to the deck at
+// destPath under a fresh ID there, then removes it from this deck. The card
+// is only removed here once the destination was written successfully.
+func (app *FlashcardApp) moveCard(cardID int, destPath string) error {
+	index, found := app.CardIndex(cardID)
+	if !found {
+		return fmt.Errorf("card with ID %d not found in '%s'", cardID, app.FilePath)
+	}
+	if app.isOpenDeck(destPath) {
+		return fmt.Errorf("'%s' is the current deck", destPath)
+	}
+
+	dest := &FlashcardApp{
+		Deck:                &deck.Deck{FilePath: destPath, DryRun: app.DryRun},
+		PostSaveHook:        app.PostSaveHook,
+		PostSaveHookTimeout: app.PostSaveHookTimeout,
+	}
+	// The destination is locked like the current deck, so another instance
+	// that has it open can't drop the moved card with its next save.
+	if !app.DryRun {
+		if err := dest.acquireLock(); err != nil {
+			return fmt.Errorf("could not open destination deck: %w", err)
+		}
+		defer dest.releaseLock()
+	}
+	if err := dest.loadFlashcards(); err != nil {
+		return fmt.Errorf("could not load destination deck: %w", err)
+	}
+
+	moved := app.Flashcards[index]
+	moved.ID = dest.NextID()
+	dest.Flashcards = append(dest.Flashcards, moved)
+	if err := dest.saveFlashcards(); err != nil {
+		return fmt.Errorf("could not write destination deck, card kept in '%s': %w", app.FilePath, err)
+	}
+
+	kept := app.Flashcards[index]
+	app.Flashcards = append(app.Flashcards[:index], app.Flashcards[index+1:]...)
+	if err := app.saveFlashcards(); err != nil {
+		// Undo the move on both sides so the card is in exactly one deck.
+		app.Flashcards = slices.Insert(app.Flashcards, index, kept)
+		dest.Flashcards = dest.Flashcards[:len(dest.Flashcards)-1]
+		if rollbackErr := dest.saveFlashcards(); rollbackErr != nil {
+			return fmt.Errorf("card copied to '%s' but could not be removed from '%s' (%v), nor taken out of '%s' again: %w", destPath, app.FilePath, err, destPath, rollbackErr)
+		}
+		return fmt.Errorf("could not remove the card from '%s', so it was taken out of '%s' again: %w", app.FilePath, destPath, err)
+	}
+	pterm.Success.Printf("Moved card (ID: %d) to '%s' as ID %d: %s\n", cardID, destPath, moved.ID, moved.Question)
+	return nil
+}
+
+// mergeFile adds the cards of another deck file with deck.Merge and saves
+// the deck if anything changed.
+func (app *FlashcardApp) mergeFile(otherPath string) (deck.MergeResult, error) {
+	if app.isOpenDeck(otherPath) {
+		return deck.MergeResult{}, fmt.Errorf("'%s' is the current deck", otherPath)
+	}
+	if _, err := os.Stat(otherPath); err != nil {
+		return deck.MergeResult{}, err
+	}
+	other := &FlashcardApp{Deck: &deck.Deck{FilePath: otherPath, DryRun: app.DryRun}}
+	if err := other.loadFlashcards(); err != nil {
+		return deck.MergeResult{}, fmt.Errorf("could not load '%s': %w", otherPath, err)
+	}
+
+	result := app.Merge(other.Deck)
+	if result.Added+result.Deduped > 0 {
+		if err := app.saveFlashcards(); err != nil {
+			return deck.MergeResult{}, err
+		}
+	}
+	return result, nil
+}
+
+func sameFile(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+	return absA == absB
+}
+
+// isOpenDeck reports whether path is one of the decks open in app.
+func (app *FlashcardApp) isOpenDeck(path string) bool {
+	for _, open := range app.Paths() {
+		if sameFile(open, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// selectDeck lets the user pick another deck file next to the current one
+// or type in any other path.
+func (app *FlashcardApp) selectDeck(prompt string) string {
+	const otherPath = "[Enter another path]"
+
+	options := []string{}
+	matches, _ := filepath.Glob(filepath.Join(filepath.Dir(app.FilePath), "*.json"))
+	for _, match := range matches {
+		if !app.isOpenDeck(match) {
+			options = append(options, match)
+		}
+	}
+	options = append(options, otherPath)
+
+	selected, _ := pterm.DefaultInteractiveSelect.
+		WithOptions(options).
+		WithDefaultText(prompt).
+		Show()
+	if selected == otherPath {
+		selected, _ = pterm.DefaultInteractiveTextInput.Show("Path to deck file")
+	}
+	return strings.TrimSpace(selected)
+}
+
+// selectCategory lets the user pick a category, or "" for all when allowAll
+// is set. Extra choices are listed after "[All Categories]" and returned
+// as they are.
+func (app *FlashcardApp) selectCategory(prompt string, allowAll bool, extra ...string) string {
+	categories := app.Categories()
+	if len(categories) == 0 && !allowAll {
+		pterm.Warning.Println("No categories available yet.")
+		return ""
+	}
+
+	options := []string{}
+	if allowAll {
+		options = append(options, "[All Categories]")
+	}
+	options = append(options, extra...)
+	options = append(options, categories...)
+
+	if len(options) == 0 {
+		pterm.Warning.Println("No categories defined yet.")
+		return ""
+	}
+
+	selectPrinter := pterm.DefaultInteractiveSelect.
+		WithOptions(options).
+		WithDefaultText(prompt)
+	if last := app.loadLastCategory(); last != "" && slices.Contains(categories, last) {
+		selectPrinter = selectPrinter.WithDefaultOption(last)
+	}
+	selected, _ := selectPrinter.Show()
+
+	if allowAll && selected == "[All Categories]" {
+		return ""
+	}
+	if selected == "" {
+		pterm.Warning.Println("No category selected.")
+		return ""
+	}
+	return selected
+}
+
+// selectDifficulty asks for a card's difficulty, preselecting current.
+func selectDifficulty(prompt, current string) string {
+	selected, _ := pterm.DefaultInteractiveSelect.
+		WithOptions(deck.Difficulties).
+		WithDefaultOption(current).
+		WithDefaultText(prompt).
+		Show()
+	if !deck.IsDifficulty(selected) {
+		return current
+	}
+	return selected
+}
+
+// deckDefaultScheduler is the scheduler choice that leaves a card's
+// Scheduler empty, so it follows the deck.
+const deckDefaultScheduler = "[Deck default]"
+
+// selectScheduler asks for a card's own scheduler, preselecting current.
+// It returns "" when the card should use the deck default.
+func selectScheduler(prompt, current string) string {
+	defaultOption := current
+	if !slices.Contains(schedulerNames, current) {
+		defaultOption = deckDefaultScheduler
+	}
+	selected, _ := pterm.DefaultInteractiveSelect.
+		WithOptions(append([]string{deckDefaultScheduler}, schedulerNames...)).
+		WithDefaultOption(defaultOption).
+		WithDefaultText(prompt).
+		Show()
+	if !slices.Contains(schedulerNames, selected) {
+		return ""
+	}
+	return selected
+}
+
+// selectDifficultyFilter asks which difficulty a session should be limited
+// to; "" means all of them.
+func selectDifficultyFilter(prompt string) string {
+	const all = "[All Difficulties]"
+	selected, _ := pterm.DefaultInteractiveSelect.
+		WithOptions(append([]string{all}, deck.Difficulties...)).
+		WithDefaultText(prompt).
+		Show()
+	if selected == all {
+		return ""
+	}
+	return selected
+}
+
+// validateMCOptions checks the options of a multiple-choice card: at least
+// two, no duplicates (ignoring case and surrounding spaces), every correct
+// answer among the options and at least one correct and one incorrect option.
+func validateMCOptions(options, correct []string) error {
+	if len(options) < 2 {
+		return errors.New("need at least 2 options for multiple choice")
+	}
+	seen := make(map[string]bool, len(options))
+	for _, option := range options {
+		key := strings.ToLower(strings.TrimSpace(option))
+		if key == "" {
+			return errors.New("options cannot be empty")
+		}
+		if seen[key] {
+			return fmt.Errorf("option '%s' is listed twice", option)
+		}
+		seen[key] = true
+	}
+	if len(correct) == 0 {
+		return errors.New("need at least one correct option")
+	}
+	for _, answer := range correct {
+		if !seen[strings.ToLower(strings.TrimSpace(answer))] {
+			return fmt.Errorf("correct answer '%s' is not one of the options", answer)
+		}
+	}
+	if len(seen) <= len(correct) {
+		return errors.New("need at least one incorrect option")
+	}
+	return nil
+}
+
+// generateDistractors picks up to n wrong options for a multiple-choice card
+// at random from the answers of other cards in the same category (ignoring
+// case). The correct answer and duplicates are never returned; when there are
+// not enough candidates, all of them are returned.
+func (app *FlashcardApp) generateDistractors(correct, category string, n int) []string {
+	if category == "" {
+		category = "General"
+	}
+	candidates := []string{}
+	for _, card := range app.Flashcards {
+		if card.Essay || !strings.EqualFold(card.Category, category) {
+			continue
+		}
+		answers := card.CorrectAnswers
+		if len(answers) == 0 {
+			answers = []string{card.Answer}
+		}
+		for _, answer := range answers {
+			answer = strings.TrimSpace(answer)
+			if answer == "" || strings.EqualFold(answer, strings.TrimSpace(correct)) || deck.ContainsFold(candidates, answer) {
+				continue
+			}
+			candidates = append(candidates, answer)
+		}
+	}
+	rng.Shuffle(len(candidates), func(i, j int) {
+		candidates[i], candidates[j] = candidates[j], candidates[i]
+	})
+	if n < len(candidates) {
+		candidates = candidates[:n]
+	}
+	return candidates
+}
+
+// promptNewCard asks for the remaining fields of a card whose question has
+// already been entered and adds it to the deck.
+func (app *FlashcardApp) promptNewCard(question string) {
+	answer, _ := pterm.DefaultInteractiveTextInput.Show("Enter the 'main' answer (used if not multiple choice)")
+	category, _ := pterm.DefaultInteractiveTextInput.Show("Enter category (leave blank for 'General')")
+	tags, _ := pterm.DefaultInteractiveTextInput.Show("Enter tags, comma-separated (optional)")
+
+	isMultipleChoice, _ := pterm.DefaultInteractiveConfirm.
+		WithConfirmText("y").WithRejectText("n").
+		Show("Make this a multiple choice question?")
+
+	var mcOptions []string
+	var mcCorrectAnswers []string
+	var mcOptionNotes map[string]string
+	isEssay := false
+	if !isMultipleChoice {
+		isEssay, _ = pterm.DefaultInteractiveConfirm.
+			WithConfirmText("y").WithRejectText("n").
+			Show("Is this an open question you grade yourself (the answer is a model answer)?")
+	}
+
+	if isMultipleChoice {
+		generate, _ := pterm.DefaultInteractiveConfirm.
+			WithDefaultValue(false).
+			WithConfirmText("y").WithRejectText("n").
+			Show("Generate the wrong options from other answers in this category?")
+		if generate {
+			mcOptions, mcCorrectAnswers = app.promptGeneratedOptions(answer, category)
+		}
+	}
+
+	if isMultipleChoice && len(mcOptions) == 0 {
+		pterm.Info.Println("Enter options (type 'done' when finished, need at least 2):")
+		optionCount := 1
+		for {
+			optionText, _ := pterm.DefaultInteractiveTextInput.
+				Show(fmt.Sprintf("Option %d", optionCount))
+
+			optionText = strings.TrimSpace(optionText)
+			trimmedOption := strings.ToLower(optionText)
+			if trimmedOption == "done" {
+				if err := validateMCOptions(mcOptions, mcCorrectAnswers); err != nil {
+					pterm.Warning.Printf("%v. Please add more options.\n", err)
+					continue
+				}
+				break
+			}
+			if deck.ContainsFold(mcOptions, optionText) {
+				pterm.Warning.Printf("'%s' is already an option. Please enter a different one.\n", optionText)
+				continue
+			}
+
+			if optionText != "" {
+				mcOptions = append(mcOptions, optionText)
+				isCorrect, _ := pterm.DefaultInteractiveConfirm.
+					WithConfirmText("y").WithRejectText("n").
+					Show(fmt.Sprintf("Is '%s' a correct answer?", optionText))
+				if isCorrect {
+					mcCorrectAnswers = append(mcCorrectAnswers, optionText)
+				}
+				note, _ := pterm.DefaultInteractiveTextInput.
+					Show(fmt.Sprintf("Why is '%s' right or wrong? (optional note)", optionText))
+				if note = strings.TrimSpace(note); note != "" {
+					if mcOptionNotes == nil {
+						mcOptionNotes = make(map[string]string)
+					}
+					mcOptionNotes[optionText] = note
+				}
+				optionCount++
+			} else if trimmedOption != "done" {
+				pterm.Warning.Println("Option cannot be empty. Please enter text or type 'done'.")
+			}
+		}
+	}
+
+	newCard := app.newCard(question, answer, category, mcOptions, mcCorrectAnswers)
+	newCard.Tags = parseTags(tags)
+	newCard.Essay = isEssay
+	note, _ := pterm.DefaultInteractiveTextInput.Show("Note or mnemonic shown with the answer (optional)")
+	newCard.Note = strings.TrimSpace(note)
+	attachment, _ := pterm.DefaultInteractiveTextInput.Show("Image path or URL to show with the card (optional)")
+	newCard.Attachment = strings.TrimSpace(attachment)
+	newCard.Difficulty = selectDifficulty("Difficulty", deck.DifficultyMedium)
+	newCard.Scheduler = selectScheduler("Scheduler", "")
+	newCard.OptionNotes = mcOptionNotes
+	if len(mcCorrectAnswers) > 1 {
+		newCard.MultiSelect, _ = pterm.DefaultInteractiveConfirm.
+			WithDefaultValue(false).
+			WithConfirmText("y").WithRejectText("n").
+			Show("Is this a 'select all that apply' question (all correct options must be picked)?")
+	}
+	if len(mcCorrectAnswers) > 1 && !newCard.MultiSelect {
+		const noBest = "[No single best answer]"
+		best, _ := pterm.DefaultInteractiveSelect.
+			WithOptions(append(append([]string{}, mcCorrectAnswers...), noBest)).
+			WithDefaultText("Which correct answer is the best one?").
+			Show()
+		if best != noBest {
+			newCard.PrimaryAnswer = best
+		}
+	}
+	app.appendCard(newCard)
+}
+
+// promptGeneratedOptions asks for the correct answer and the number of wrong
+// options and builds the options with generateDistractors. It returns nil
+// options when no distractors could be found, so the caller falls back to
+// entering them by hand.
+func (app *FlashcardApp) promptGeneratedOptions(answer, category string) (options, correctAnswers []string) {
+	correct := strings.TrimSpace(answer)
+	for correct == "" {
+		correct, _ = pterm.DefaultInteractiveTextInput.Show("Enter the correct answer")
+		correct = strings.TrimSpace(correct)
+	}
+	countStr, _ := pterm.DefaultInteractiveTextInput.
+		WithDefaultValue("3").
+		Show("Number of wrong options")
+	count, err := strconv.Atoi(strings.TrimSpace(countStr))
+	if err != nil || count <= 0 {
+		pterm.Warning.Println("Invalid number of wrong options, defaulting to 3.")
+		count = 3
+	}
+
+	distractors := app.generateDistractors(correct, category, count)
+	if len(distractors) == 0 {
+		pterm.Warning.Println("No other answers in this category to use. Please enter the options yourself.")
+		return nil, nil
+	}
+	if len(distractors) < count {
+		pterm.Warning.Printf("Only %d other answers in this category, using all of them.\n", len(distractors))
+	}
+	options = append([]string{correct}, distractors...)
+	rng.Shuffle(len(options), func(i, j int) {
+		options[i], options[j] = options[j], options[i]
+	})
+	pterm.Info.Printf("Options: %s (correct: %s)\n", strings.Join(options, ", "), correct)
+	return options, []string{correct}
+}
+
+// addMultipleCards repeats the add prompts until an empty question is entered.
+func (app *FlashcardApp) addMultipleCards() {
+	pterm.Info.Println("Adding cards in a row. Leave the question empty to stop.")
+	added := 0
+	for {
+		question, _ := pterm.DefaultInteractiveTextInput.Show("Enter question")
+		if strings.TrimSpace(question) == "" {
+			break
+		}
+		app.promptNewCard(question)
+		added++
+		fmt.Println()
+	}
+	pterm.Info.Printf("Finished adding cards (%d entered).\n", added)
+}
+
+// menuItem is one entry of the main menu. The menu, the dispatch in main and
+// the help screen are all generated from mainMenu so they stay in sync.
+type menuItem struct {
+	Action string
+	Label  string
+	Help   string
+}
+
+var mainMenu = []menuItem{
+	{"add", "Add new flashcard", "Enter question, answer, category and optional multiple-choice options."},
+	{"add-multiple", "Add multiple flashcards", "Repeat the add prompts until an empty question is entered."},
+	{"quick-add", "Quick add", "Add text cards in one line each: Category :: Question :: Answer1 | Answer2."},
+	{"study-due", "Study due cards", "Review every card that is due today, the most overdue first."},
+	{"review", "Review flashcards", "Go through cards and self-grade whether you knew the answer."},
+	{"review-boxes", "Review due boxes", "Show the Leitner boxes and review their due cards with the Leitner scheduler."},
+	{"mistakes", "Review mistakes", "Review only the cards you answered wrong the last time, due or not."},
+	{"quiz", "Quiz mode", "Answer a set number of questions by typing or choosing an option."},
+	{"cram", "Cram mode", "Quiz the selected cards again and again until every one is answered correctly."},
+	{"undo", "Undo last session", "Restore the stats from before the last review or quiz."},
+	{"profiles", "Study profiles", "Run, create or delete saved session settings for this deck."},
+	{"list", "List flashcards", "Show a table of cards, all or by category."},
+	{"tags", "Browse by tag", "Show all tags with their card counts and list the cards of one."},
+	{"unreviewed", "Show unreviewed", "List the cards that were never reviewed, all or by category."},
+	{"show", "View card detail", "Show one card in full: question, answers, options, notes and stats."},
+	{"stats", "Statistics", "Show card counts, accuracy per category and the weakest cards."},
+	{"leaderboard", "Leaderboard", "Show your best quiz scores per category."},
+	{"trend", "Show trend", "Show your review and quiz accuracy per week."},
+	{"edit", "Edit a flashcard", "Change a card's text, category or options while keeping its stats."},
+	{"edit-correct", "Edit correct answers", "Toggle which options of a multiple-choice card are correct."},
+	{"rename-category", "Rename/merge category", "Rename a category; naming it like an existing one merges the two."},
+	{"replace", "Find and replace", "Replace a misspelled term in the questions and/or answers of all cards."},
+	{"search", "Search flashcards", "Find cards by keyword or scoped terms like category:French accuracy:<50."},
+	{"duplicates", "Find duplicates", "List groups of cards that ask the same question."},
+	{"delete", "Delete a flashcard", "Remove a card by its ID."},
+	{"bulk-delete", "Bulk delete", "Move all cards of a category or an ID range to the trash."},
+	{"trash", "Trash", "View deleted cards, restore one or empty the trash for good."},
+	{"move", "Move a flashcard to another deck", "Move a card with its stats into another deck file."},
+	{"flagged", "Flagged cards", "Fix or delete cards flagged during review or quiz."},
+	{"reset", "Reset stats", "Clear review stats and schedules of all cards or one category."},
+	{"reverse", "Toggle reverse mode", "Switch between asking question-to-answer and answer-to-question."},
+	{"help", "Help", "Show this overview of actions, keys and flags."},
+	{"exit", "Exit", "Close the application."},
+}
+
+// reviewKeys lists the single-key actions available while a card is shown
+// in review mode.
+var reviewKeys = []menuItem{
+	{"continue", "Enter", "Reveal the options or the answer."},
+	{"flag", "f", "Flag the card as wrong or confusing, then reveal."},
+	{"?", "?", "Show this help."},
+}
+
+func menuOptions() []string {
+	options := make([]string, len(mainMenu))
+	for i, item := range mainMenu {
+		options[i] = fmt.Sprintf("%d. %s", i+1, item.Label)
+	}
+	return options
+}
+
+func showHelp() {
+	var b strings.Builder
+	b.WriteString(pterm.Bold.Sprint("Menu actions") + "\n")
+	for i, item := range mainMenu {
+		fmt.Fprintf(&b, "  %d. %-26s %s\n", i+1, item.Label, item.Help)
+	}
+	b.WriteString("\n" + pterm.Bold.Sprint("Review keys") + "\n")
+	for _, key := range reviewKeys {
+		fmt.Fprintf(&b, "  %-8s %s\n", key.Label, key.Help)
+	}
+	b.WriteString("\n" + pterm.Bold.Sprint("Command-line flags") + "\n")
+	flag.VisitAll(func(f *flag.Flag) {
+		if !hiddenFlags[f.Name] {
+			fmt.Fprintf(&b, "  -%-22s %s\n", f.Name, f.Usage)
+		}
+	})
+	pterm.DefaultBox.WithTitle("Help").Println(strings.TrimRight(b.String(), "\n"))
+}
+
+// waitForReveal pauses until the user continues, showing the help screen
+// whenever '?' is pressed and flagging the card on 'f'.
+func (app *FlashcardApp) waitForReveal(card Flashcard, text string) {
+	actions := make([]string, len(reviewKeys))
+	for i, key := range reviewKeys {
+		actions[i] = key.Action
+	}
+	for {
+		result, _ := pterm.DefaultInteractiveContinue.WithOptions(actions).Show(text)
+		switch result {
+		case "?":
+			showHelp()
+		case "flag":
+			app.promptFlag(card.ID)
+			return
+		default:
+			return
+		}
+	}
+}
+
+// promptFlag asks for an optional reason and flags the card. The flag is
+// saved together with the rest of the session.
+func (app *FlashcardApp) promptFlag(cardID int) {
+	index, found := app.CardIndex(cardID)
+	if !found {
+		pterm.Error.Printf("Could not find card with ID %d to flag.\n", cardID)
+		return
+	}
+	reason, _ := pterm.DefaultInteractiveTextInput.Show("Flag reason (optional)")
+	app.Flashcards[index].Flagged = true
+	app.Flashcards[index].FlagReason = strings.TrimSpace(reason)
+	pterm.Info.Printf("Card %d flagged for later review.\n", cardID)
+}
+
+// reviewFlaggedCards lists flagged cards and lets the user edit, clear the
+// flag of or delete each one.
+func (app *FlashcardApp) reviewFlaggedCards() {
+	for {
+		flagged := deck.OnlyFlagged(app.Flashcards)
+		if len(flagged) == 0 {
+			pterm.Info.Println("No flagged cards.")
+			return
+		}
+		sortCards(flagged, "id")
+
+		tableData := pterm.TableData{{"ID", "Category", "Question", "Reason"}}
+		choices := []string{}
+		for _, card := range flagged {
+			reason := card.FlagReason
+			if reason == "" {
+				reason = "-"
+			}
+			tableData = append(tableData, []string{strconv.Itoa(card.ID), card.Category, card.Question, reason})
+			choices = append(choices, fmt.Sprintf("%d: %s", card.ID, card.Question))
+		}
+		pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+
+		choices = append(choices, "Back")
+		selected, _ := pterm.DefaultInteractiveSelect.
+			WithOptions(choices).
+			WithDefaultText("Select a flagged card").
+			Show()
+		if selected == "" || selected == "Back" {
+			return
+		}
+		id, err := strconv.Atoi(strings.SplitN(selected, ":", 2)[0])
+		if err != nil {
+			return
+		}
+
+		action, _ := pterm.DefaultInteractiveSelect.
+			WithOptions([]string{"Edit card", "Clear flag (fixed)", "Delete card", "Back"}).
+			WithDefaultText(fmt.Sprintf("What should happen to card %d?", id)).
+			Show()
+		switch action {
+		case "Edit card":
+			app.editCard(id)
+		case "Clear flag (fixed)":
+			index, found := app.CardIndex(id)
+			if !found {
+				continue
+			}
+			app.Flashcards[index].Flagged = false
+			app.Flashcards[index].FlagReason = ""
+			if err := app.saveFlashcards(); err == nil {
+				pterm.Success.Printf("Cleared flag of card %d.\n", id)
+			}
+		case "Delete card":
+			app.deleteCard(id)
+		}
+		fmt.Println()
+	}
+}
+
+// config holds the defaults read from ~/.flashcardsrc. Unset fields keep the
+// built-in defaults.
+type config struct {
+	File    string `json:"file"`
+	Fuzzy   *bool  `json:"fuzzy"`
+	DelayMs *int   `json:"delay_ms"`
+}
+
+// loadConfig reads ~/.flashcardsrc. A missing file is an empty config.
+func loadConfig() (config, error) {
+	cfg := config{}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return cfg, nil
+	}
+	path := filepath.Join(home, ".flashcardsrc")
+	data, err := ioutil.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, err
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return config{}, fmt.Errorf("%s: %w", path, err)
+	}
+	if rest, ok := strings.CutPrefix(cfg.File, "~/"); ok {
+		cfg.File = filepath.Join(home, rest)
+	}
+	return cfg, nil
+}
+
+// loadConfigOrWarn is loadConfig for startup: a broken config file is
+// reported and ignored.
+func loadConfigOrWarn() config {
+	cfg, err := loadConfig()
+	if err != nil {
+		pterm.Warning.Printf("Ignoring config file: %v\n", err)
+	}
+	return cfg
+}
+
+// flagWasSet reports whether the named flag was given on the command line.
+func flagWasSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// deckPath picks the deck file: the -file flag, then the FLASHCARDS_FILE
+// environment variable, then the config file, then the flag's default.
+func deckPath(fs *flag.FlagSet, flagValue string, cfg config) string {
+	if flagWasSet(fs, "file") {
+		return flagValue
+	}
+	if env := os.Getenv("FLASHCARDS_FILE"); env != "" {
+		return env
+	}
+	if cfg.File != "" {
+		return cfg.File
+	}
+	return flagValue
+}
+
+// runSubcommand handles the non-interactive commands "add", "list" and
+// "delete" and returns the process exit code.
+func runSubcommand(name string, args []string) int {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	filePath := fs.String("file", "flashcards.json", "Path to the flashcards JSON file (default from $FLASHCARDS_FILE or ~/.flashcardsrc)")
+	cfg := loadConfigOrWarn()
+
+	switch name {
+	case "add":
+		question := fs.String("question", "", "Question text (required)")
+		answer := fs.String("answer", "", "Answer text (required)")
+		category := fs.String("category", "", "Category (default General)")
+		options := fs.String("options", "", "Multiple-choice options separated by '|'")
+		correct := fs.String("correct", "", "Correct options separated by '|' (default: first option)")
+		difficulty := fs.String("difficulty", deck.DifficultyMedium, "Difficulty: easy, medium or hard")
+		scheduler := fs.String("scheduler", "", "Scheduler of this card: none, sm2, leitner or fsrs (default: the deck's)")
+		tags := fs.String("tags", "", "Tags separated by commas")
+		note := fs.String("note", "", "Note or mnemonic shown with the answer")
+		essay := fs.Bool("essay", false, "Open question graded by yourself; --answer is the model answer")
+		attachment := fs.String("attachment", "", "Image path or URL shown with the card")
+		multiSelect := fs.Bool("multi-select", false, "Select-all-that-apply question: every --correct option must be picked")
+		allowDuplicate := fs.Bool("allow-duplicate", false, "Add the card even if another card has the same question")
+		if err := fs.Parse(args); err != nil {
+			return 2
+		}
+		if strings.TrimSpace(*question) == "" || strings.TrimSpace(*answer) == "" {
+			pterm.Error.Println("add needs both --question and --answer.")
+			return 2
+		}
+		if !deck.IsDifficulty(*difficulty) {
+			pterm.Error.Printf("Unknown difficulty '%s' (use easy, medium or hard).\n", *difficulty)
+			return 2
+		}
+		if *scheduler != "" && !slices.Contains(schedulerNames, *scheduler) {
+			pterm.Error.Printf("Unknown scheduler '%s' (use %s).\n", *scheduler, strings.Join(schedulerNames, ", "))
+			return 2
+		}
+		app, err := openLockedDeck(deckPath(fs, *filePath, cfg))
+		if err != nil {
+			pterm.Error.Printf("Could not open the deck: %v.\n", err)
+			return 1
+		}
+		defer app.releaseLock()
+		if existing, found := app.FindByQuestion(*question); found && !*allowDuplicate {
+			pterm.Error.Printf("Card %d already asks '%s' (use --allow-duplicate to add it anyway).\n", existing.ID, existing.Question)
+			return 1
+		}
+		app.AllowDuplicates = true
+		card := app.newCard(strings.TrimSpace(*question), strings.TrimSpace(*answer), strings.TrimSpace(*category), splitList(*options), splitList(*correct))
+		card.Difficulty = *difficulty
+		card.Scheduler = *scheduler
+		card.Tags = parseTags(*tags)
+		if *essay && len(card.Options) > 0 {
+			pterm.Error.Println("--essay can't be combined with --options.")
+			return 2
+		}
+		card.Essay = *essay
+		card.Note = strings.TrimSpace(*note)
+		card.Attachment = strings.TrimSpace(*attachment)
+		if *multiSelect && len(card.CorrectAnswers) < 2 {
+			pterm.Error.Println("--multi-select needs --options with at least two --correct options.")
+			return 2
+		}
+		card.MultiSelect = *multiSelect
+		if len(card.Options) > 0 {
+			if err := validateMCOptions(card.Options, card.CorrectAnswers); err != nil {
+				pterm.Error.Printf("Invalid options: %v.\n", err)
+				return 2
+			}
+		}
+		if !app.appendCard(card) {
+			return 1
+		}
+		return 0
+
+	case "list":
+		category := fs.String("category", "", "Only list cards in this category")
+		format := fs.String("format", "table", "Output format: table, json or csv")
+		showAll := fs.Bool("all", false, "Include mastered cards")
+		tag := fs.String("tag", "", "Only list cards with this tag")
+		if err := fs.Parse(args); err != nil {
+			return 2
+		}
+		if err := checkListFormat(*format); err != nil {
+			pterm.Error.Println(err)
+			return 2
+		}
+		if *format != "table" {
+			useStderrForMessages()
+		}
+		app := NewFlashcardApp(deckPath(fs, *filePath, cfg))
+		app.HideMastered = !*showAll
+		cards := deck.FilterByCategory(app.Flashcards, *category)
+		if *tag != "" {
+			cards = deck.FilterByTag(cards, *tag)
+		}
+		cards, hidden := app.withoutMastered(cards)
+		if hidden > 0 {
+			pterm.Info.Printf("+%d mastered hidden (use --all to show them).\n", hidden)
+		}
+		if err := app.printCards(cards, *format); err != nil {
+			pterm.Error.Printf("Could not list flashcards: %v\n", err)
+			return 1
+		}
+		return 0
+
+	case "delete":
+		id := fs.Int("id", 0, "ID of the card to delete (required)")
+		force := fs.Bool("force", false, "Delete without showing the card and asking for confirmation")
+		if err := fs.Parse(args); err != nil {
+			return 2
+		}
+		if *id <= 0 {
+			pterm.Error.Println("delete needs a positive --id.")
+			return 2
+		}
+		app, err := openLockedDeck(deckPath(fs, *filePath, cfg))
+		if err != nil {
+			pterm.Error.Printf("Could not open the deck: %v.\n", err)
+			return 1
+		}
+		defer app.releaseLock()
+		app.Force = *force
+		if !app.deleteCard(*id) {
+			return 1
+		}
+		return 0
+	}
+
+	pterm.Error.Printf("Unknown command '%s'. Available commands: add, list, delete.\n", name)
+	return 2
+}
+
+// saveOnInterrupt makes Ctrl-C and SIGTERM end the program through
+// handleInterrupt. pterm prompts read Ctrl-C as a key press, so their
+// interrupt hooks are set as well as a signal handler.
+func (app *FlashcardApp) saveOnInterrupt() {
+	pterm.DefaultInteractiveSelect.OnInterruptFunc = app.handleInterrupt
+	pterm.DefaultInteractiveMultiselect.OnInterruptFunc = app.handleInterrupt
+	pterm.DefaultInteractiveConfirm.OnInterruptFunc = app.handleInterrupt
+	pterm.DefaultInteractiveTextInput.OnInterruptFunc = app.handleInterrupt
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		app.handleInterrupt()
+	}()
+}
+
+// handleInterrupt saves the cards answered so far if a review, quiz or cram
+// session is running, then exits. It holds sessionMu until the exit, so the
+// session can't change or save the cards meanwhile.
+func (app *FlashcardApp) handleInterrupt() {
+	fmt.Println()
+	app.sessionMu.Lock()
+	if app.inSession {
+		if err := app.saveFlashcards(); err == nil {
+			pterm.Success.Println("Interrupted. The progress of this session was saved.")
+		}
+	}
+	app.releaseLock()
+	os.Exit(130)
+}
+
+// hiddenFlags are developer flags left out of the usage text and the help
+// screen.
+var hiddenFlags = map[string]bool{"gen": true, "bench": true}
+
+// printUsage is flag.Usage without the hiddenFlags.
+func printUsage() {
+	visible := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	visible.SetOutput(flag.CommandLine.Output())
+	flag.VisitAll(func(f *flag.Flag) {
+		if !hiddenFlags[f.Name] {
+			visible.Var(f.Value, f.Name, f.Usage)
+			visible.Lookup(f.Name).DefValue = f.DefValue
+		}
+	})
+	fmt.Fprintf(visible.Output(), "Usage of %s:\n", os.Args[0])
+	visible.PrintDefaults()
+}
+
+// benchQuestions is the length of the simulated quiz run by -bench.
+const benchQuestions = 100
+
+// generateCards adds n synthetic cards to the deck and saves it once. Every
+// fourth card is multiple choice. The questions and answers only depend on
+// rng, so -seed makes the generated deck reproducible.
+func (app *FlashcardApp) generateCards(n int) error {
+	categories := []string{"Math", "History", "Science", "Language", "Geography"}
+	for i := 0; i < n; i++ {
+		a, b := rng.Intn(1000), rng.Intn(1000)
+		answer := strconv.Itoa(a + b)
+		var options []string
+		if i%4 == 3 {
+			options = []string{answer, strconv.Itoa(a + b + 1), strconv.Itoa(a + b - 1), strconv.Itoa(a + b + 10)}
+		}
+		card := app.newCard("", answer, categories[rng.Intn(len(categories))], options, []string{answer})
+		card.Question = fmt.Sprintf("Synthetic %d: what is %d + %d?", card.ID, a, b)
+		app.Flashcards = append(app.Flashcards, card)
+	}
+	return app.saveFlashcards()
+}
+
+// runBenchmark times loading the deck(s) at filePath, rendering the full
+// card list and a simulated quiz of benchQuestions questions, to catch
+// slowdowns on large decks. Nothing is saved.
+func runBenchmark(filePath string) error {
+	start := time.Now()
+	app := newDeckApp(filePath)
+	app.ReadOnly = true
+	if err := app.loadFlashcards(); err != nil {
+		return err
+	}
+	loadTime := time.Since(start)
+	if len(app.Flashcards) == 0 {
+		return errors.New("the deck has no cards, generate some with -gen")
+	}
+
+	pterm.SetDefaultOutput(ioutil.Discard)
+	start = time.Now()
+	app.renderCardTable(slices.Clone(app.Flashcards))
+	listTime := time.Since(start)
+
+	start = time.Now()
+	correct := app.simulateQuiz(benchQuestions)
+	quizTime := time.Since(start)
+	pterm.SetDefaultOutput(os.Stdout)
+
+	pterm.DefaultTable.WithHasHeader().WithData(pterm.TableData{
+		{"Step", "Duration"},
+		{fmt.Sprintf("Load %d cards", len(app.Flashcards)), loadTime.String()},
+		{"Render the card list", listTime.String()},
+		{fmt.Sprintf("Quiz of %d questions (%d correct)", min(benchQuestions, len(app.Flashcards)), correct), quizTime.String()},
+	}).Render()
+	return nil
+}
+
+// simulateQuiz runs the answer checking and stat updates of a quiz without
+// prompting: every third card is answered wrong, the others with their first
+// correct answer. It returns the number of correct answers.
+func (app *FlashcardApp) simulateQuiz(numQuestions int) int {
+	cards := app.sessionOrder(app.Flashcards)
+	if numQuestions > len(cards) {
+		numQuestions = len(cards)
+	}
+	correctCount := 0
+	for i, card := range cards[:numQuestions] {
+		// Cards from older files may have no correct answers stored.
+		answers := card.CorrectAnswers
+		if len(answers) == 0 {
+			answers = []string{card.Answer}
+		}
+		given := "wrong"
+		if i%3 != 2 {
+			given = answers[0]
+		}
+		isCorrect := false
+		for _, correctAnswer := range answers {
+			if app.sameAnswer(given, correctAnswer) {
+				isCorrect = true
+				break
+			}
+		}
+		if index, found := app.CardIndex(card.ID); found {
+			now := time.Now()
+			app.Flashcards[index].TimesReviewed++
+			app.Flashcards[index].LastReviewed = &now
+			app.Flashcards[index].LastCorrect = &isCorrect
+			if isCorrect {
+				app.Flashcards[index].TimesCorrect++
+			}
+		}
+		if isCorrect {
+			correctCount++
+		}
+	}
+	return correctCount
+}
+
+// useStderrForMessages routes pterm's status messages to stderr so stdout
+// only carries machine-readable output.
+func useStderrForMessages() {
+	pterm.SetDefaultOutput(os.Stderr)
+	pterm.Info.Writer = os.Stderr
+	pterm.Success.Writer = os.Stderr
+	pterm.Warning.Writer = os.Stderr
+	pterm.Error.Writer = os.Stderr
+}
+
+func main() {
+	if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
+		os.Exit(runSubcommand(os.Args[1], os.Args[2:]))
+	}
+
+	filePath := flag.String("file", "flashcards.json", "Path to the flashcards JSON file, or several comma-separated files to study together (default from $FLASHCARDS_FILE or ~/.flashcardsrc)")
+	sortBy := flag.String("sort", "id", "Sort order for listed cards: id or time (total time spent, most first)")
+	repeatMissed := flag.Bool("repeat-missed-at-end", false, "In review mode, repeat missed cards after the main pass until all are answered correctly")
+	requeue := flag.Bool("requeue", false, "In review mode, show each missed card once more at the end of the session; only the first attempt counts")
+	list := flag.Bool("list", false, "Print the flashcards and exit")
+	category := flag.String("category", "", "Category for non-interactive commands (filter, or target category for imports)")
+	format := flag.String("format", "table", "Output format for -list and -unreviewed: table, json or csv")
+	unreviewed := flag.Bool("unreviewed", false, "Print the cards that were never reviewed (honours -category and -format) and exit")
+	hideAnswers := flag.Bool("hide-answers-in-list", false, "Show *** instead of the answers in card lists, to study before testing")
+	revealStyle := flag.String("reveal-style", revealInstant, "How review reveals answers: instant, or wait for an extra confirmation")
+	pageSize := flag.Int("page-size", 20, "Cards per page in the interactive card list (0 = no paging)")
+	hideMastered := flag.Bool("hide-mastered", true, "Hide mastered cards from card lists by default")
+	showAll := flag.Bool("all", false, "Show mastered cards in card lists for this run")
+	search := flag.String("search", "", "Print the cards matching this search query and exit (honours -format)")
+	flaggedOnly := flag.Bool("flagged", false, "With -list, only print flagged cards")
+	tag := flag.String("tag", "", "Only use cards with this tag in review, quiz, cram and card lists")
+	importQuizlet := flag.String("import-quizlet", "", "Import cards from a Quizlet export file and exit")
+	quizletTermSep := flag.String("quizlet-term-sep", `\t`, "Separator between term and definition in a Quizlet export")
+	quizletRowSep := flag.String("quizlet-row-sep", `\n`, "Separator between rows in a Quizlet export")
+	merge := flag.String("merge", "", "Merge the cards of another deck file into this one and exit")
+	importTxt := flag.String("import-txt", "", "Import cards from a text file of Q:/A:/C: blocks and exit")
+	importAnki := flag.String("import-anki", "", "Import cards from an Anki plain-text (tab-separated) export and exit")
+	newPerDay := flag.Int("new-per-day", 0, "Most never-reviewed cards to take into review and quiz per day (0 = no limit)")
+	reviewsPerDay := flag.Int("reviews-per-day", 0, "Most already-seen cards to review or quiz per day (0 = no limit)")
+	leitnerBoxes := flag.String("leitner-intervals", "1,2,4,8,16", "Review interval in days of each Leitner box, comma-separated; the number of values sets the number of boxes")
+	scheduler := flag.String("scheduler", schedulerSM2, "Scheduler for cards without their own: none, sm2, leitner or fsrs; stored in the deck (default: the deck's, else sm2)")
+	exportCSV := flag.String("export-csv", "", "Export all cards to this CSV file and exit")
+	importCSV := flag.String("import-csv", "", "Import cards from this CSV file and exit")
+	exportJSONL := flag.String("export-jsonl", "", "Export all cards to this JSON lines file (one card per line) and exit")
+	importJSONL := flag.String("import-jsonl", "", "Import cards from a JSON lines file, skipping malformed lines, and exit")
+	importMode := flag.String("import-mode", importSkipDupes, "What imports do with cards whose question is already in the deck: append, skip-dupes or replace (update answers and options, keep stats)")
+	exportCategory := flag.String("export-category", "", "Write the cards of -category to this new deck file, numbered from 1, and exit")
+	exportMD := flag.String("export-md", "", "Write a Markdown study sheet of the cards (see -category) to this file and exit")
+	exportProgress := flag.String("export-progress", "", "Write only the review progress of each card to this file and exit")
+	importProgress := flag.String("import-progress", "", "Merge review progress from this file onto the deck by card ID and exit")
+	numericMC := flag.Bool("numeric-mc", false, "Answer multiple-choice quiz questions by typing the option number instead of using the arrow keys")
+	stableOptions := flag.Bool("stable-options", false, "Show multiple-choice options in a fixed per-card order instead of reshuffling them every time")
+	deleteCategory := flag.String("delete-category", "", "Move all cards of this category to the trash and exit (asks first unless -force)")
+	deleteRange := flag.String("delete-range", "", "Move the cards with IDs in this range, e.g. 10-25, to the trash and exit (asks first unless -force)")
+	dryRun := flag.Bool("dry-run", false, "Never write the deck or its side files; report what would be saved instead")
+	force := flag.Bool("force", false, "Delete cards without showing them and asking for confirmation first; let -export-category replace an existing file")
+	numericAnswers := flag.Bool("numeric-answers", false, "Compare typed answers that are numbers by value, so 1,000 matches 1000 and 3.0 matches 3")
+	caseSensitive := flag.Bool("case-sensitive", false, "Quiz answers must match the case of the stored answer (turns off -fuzzy)")
+	fuzzy := flag.Bool("fuzzy", false, "Accept quiz text answers within a small edit distance of the correct answer")
+	fuzzyThreshold := flag.Float64("fuzzy-threshold", 0.15, "Allowed edit distance for -fuzzy as a share of the answer length")
+	partialCredit := flag.Float64("partial-credit", 0.5, "Quiz points for an acceptable answer when a card marks a different one as best")
+	postSaveHook := flag.String("post-save-hook", "", "Command to run after each successful save; the deck path is passed as the last argument")
+	postSaveHookTimeout := flag.Duration("post-save-hook-timeout", 30*time.Second, "Maximum run time of the post-save hook")
+	summary := flag.String("summary", "full", "End-of-session summary: brief (score only) or full (per-category breakdown and missed cards)")
+	minInterval := flag.Duration("min-interval", 0, "Shortest interval the scheduler may pick, e.g. 24h (rounded up to whole days)")
+	maxInterval := flag.Duration("max-interval", 0, "Longest interval the scheduler may pick, e.g. 4320h (rounded down to whole days, 0 = no limit)")
+	profileName := flag.String("profile", "", "Run the study profile with this name and exit")
+	weakThreshold := flag.Float64("weak-threshold", 60, "Accuracy in percent below which a category is reported as weak at startup")
+	quiet := flag.Bool("quiet", false, "Don't print the summary of stale cards and weak categories at startup")
+	noWeakAlert := flag.Bool("no-weak-alert", false, "Don't report weak categories at startup")
+	stats := flag.Bool("stats", false, "Print deck statistics and exit")
+	show := flag.Int("show", 0, "Print the full details of the card with this ID and exit")
+	validate := flag.Bool("validate", false, "Check all cards for problems such as correct answers missing from the options and exit")
+	lastCategory := flag.Bool("last-category", true, "Pre-select the category of the last review, quiz or cram session in category menus")
+	forgetCategory := flag.Bool("forget-category", false, "Forget the remembered category and exit")
+	resetStats := flag.Bool("reset-stats", false, "Clear the review stats of all cards (or those in -category) and exit")
+	limit := flag.Int("limit", 0, "Review at most this many cards, picking the least recently reviewed ones (0 = no limit)")
+	mistakes := flag.Bool("mistakes", false, "Limit review and quiz to cards answered wrong the last time (review ignores due dates then)")
+	weighted := flag.Bool("weighted", false, "Pick quiz questions at random with a bias towards cards you often get wrong or never reviewed")
+	noShuffle := flag.Bool("no-shuffle", false, "Present review and quiz cards in ID order instead of shuffling them")
+	seed := flag.Int64("seed", 0, "Seed for shuffling, for reproducible sessions (0 = random)")
+	reverse := flag.Bool("reverse", false, "Show the answer and ask for the question in review and quiz (text cards only)")
+	requireAll := flag.Bool("require-all", false, "Text quiz cards with several correct answers need all of them, comma-separated")
+	timed := flag.Bool("timed", false, "Give each quiz question a time limit (see -time-per-q)")
+	timePerQ := flag.Int("time-per-q", 15, "Seconds per question in a -timed quiz")
+	delay := flag.Int("delay", defaultDelayMs, "Pause in milliseconds after each quiz answer (0 = no pause)")
+	gen := flag.Int("gen", 0, "Add this many synthetic cards to the deck and exit (deterministic with -seed)")
+	bench := flag.Bool("bench", false, "Time loading, listing and a simulated 100-question quiz on the deck and exit")
+
+	flag.Usage = printUsage
+	flag.Parse()
+
+	cfg := loadConfigOrWarn()
+	*filePath = deckPath(flag.CommandLine, *filePath, cfg)
+	if cfg.Fuzzy != nil && !flagWasSet(flag.CommandLine, "fuzzy") {
+		*fuzzy = *cfg.Fuzzy
+	}
+	if cfg.DelayMs != nil && !flagWasSet(flag.CommandLine, "delay") {
+		*delay = *cfg.DelayMs
+	}
+
+	if *seed != 0 {
+		rng = rand.New(rand.NewSource(*seed))
+	}
+
+	if err := checkListFormat(*format); err != nil {
+		pterm.Error.Println(err)
+		os.Exit(2)
+	}
+	if err := checkImportMode(*importMode); err != nil {
+		pterm.Error.Println(err)
+		os.Exit(2)
+	}
+	if minDays, maxDays := intervalDays(*minInterval, *maxInterval); *maxInterval > 0 {
+		if maxDays < 1 {
+			pterm.Error.Printf("-max-interval (%s) must be at least 24h, as intervals are whole days.\n", *maxInterval)
+			os.Exit(1)
+		}
+		if minDays > maxDays {
+			pterm.Error.Printf("-min-interval (%s, %d day(s)) must not be greater than -max-interval (%s, %d day(s)).\n", *minInterval, minDays, *maxInterval, maxDays)
+			os.Exit(1)
+		}
+	}
+	if !slices.Contains(schedulerNames, *scheduler) {
+		pterm.Error.Printf("Unknown -scheduler '%s' (use %s).\n", *scheduler, strings.Join(schedulerNames, ", "))
+		os.Exit(2)
+	}
+	if *revealStyle != revealInstant && *revealStyle != revealWait {
+		pterm.Error.Printf("Unknown -reveal-style '%s' (use instant or wait).\n", *revealStyle)
+		os.Exit(2)
+	}
+	if *limit < 0 {
+		pterm.Error.Println("-limit must not be negative.")
+		os.Exit(1)
+	}
+	if *newPerDay < 0 || *reviewsPerDay < 0 {
+		pterm.Error.Println("-new-per-day and -reviews-per-day must not be negative.")
+		os.Exit(1)
+	}
+	if *timed && *timePerQ <= 0 {
+		pterm.Error.Println("-time-per-q must be a positive number of seconds.")
+		os.Exit(1)
+	}
+	intervals, err := parseLeitnerIntervals(*leitnerBoxes)
+	if err != nil {
+		pterm.Error.Printf("Invalid -leitner-intervals: %v.\n", err)
+		os.Exit(2)
+	}
+	leitnerIntervals = intervals
+	var deleteFrom, deleteTo int
+	if *deleteRange != "" {
+		if deleteFrom, deleteTo, err = parseIDRange(*deleteRange); err != nil {
+			pterm.Error.Println(err)
+			os.Exit(2)
+		}
+	}
+	if *exportCategory != "" && *category == "" {
+		pterm.Error.Println("-export-category needs -category to pick the cards.")
+		os.Exit(2)
+	}
+	if (*list || *search != "" || *unreviewed) && *format != "table" {
+		useStderrForMessages()
+	}
+
+	if *dryRun {
+		pterm.Warning.Println("DRY RUN: nothing is written to the deck or its side files; exports still write their output file.")
+	}
+	// The deck is loaded by hand instead of with NewFlashcardApp so that a
+	// format upgrade on load already honours -dry-run and happens under the
+	// lock. Read-only runs don't take the lock, so they open the deck
+	// ReadOnly: an upgrade, recovery or renumbering on load then stays in
+	// memory instead of overwriting a deck another instance has open.
+	app := newDeckApp(*filePath)
+	app.DryRun = *dryRun
+	readOnlyRun := *list || *search != "" || *unreviewed || *stats || *show != 0 || *exportCSV != "" || *exportMD != "" ||
+		*exportJSONL != "" || *exportProgress != "" || *exportCategory != "" || (*bench && *gen == 0)
+	app.ReadOnly = readOnlyRun
+	if !*dryRun && !readOnlyRun {
+		if err := app.acquireLock(); err != nil {
+			pterm.Error.Printf("Could not open the deck: %v.\n", err)
+			os.Exit(1)
+		}
+		defer app.releaseLock()
+	}
+	// exit releases the deck lock first, as os.Exit skips deferred calls.
+	exit := func(code int) {
+		app.releaseLock()
+		os.Exit(code)
+	}
+	app.loadFlashcards()
+	app.saveOnInterrupt()
+	app.PostSaveHook = *postSaveHook
+	app.PostSaveHookTimeout = *postSaveHookTimeout
+	app.SortBy = *sortBy
+	app.RepeatMissed = *repeatMissed
+	app.Requeue = *requeue
+	app.TagFilter = strings.TrimSpace(*tag)
+	app.MinInterval = *minInterval
+	app.MaxInterval = *maxInterval
+	app.Summary = *summary
+	app.StableOptions = *stableOptions
+	app.NumericMC = *numericMC
+	app.PartialCredit = *partialCredit
+	app.Fuzzy = *fuzzy
+	app.CaseSensitive = *caseSensitive
+	app.NumericAnswers = *numericAnswers
+	app.LastCategory = *lastCategory
+	app.Force = *force
+	app.ImportMode = *importMode
+	if app.CaseSensitive && app.Fuzzy {
+		pterm.Warning.Println("-case-sensitive takes precedence over -fuzzy; answers must match exactly.")
+		app.Fuzzy = false
+	}
+	app.FuzzyThreshold = *fuzzyThreshold
+	app.HideMastered = *hideMastered && !*showAll
+	app.PageSize = *pageSize
+	app.HideAnswers = *hideAnswers
+	app.RevealStyle = *revealStyle
+	if *delay < 0 {
+		pterm.Warning.Printf("-delay must not be negative, using %dms.\n", defaultDelayMs)
+		*delay = defaultDelayMs
+	}
+	app.Delay = time.Duration(*delay) * time.Millisecond
+	app.RequireAll = *requireAll
+	app.Reverse = *reverse
+	app.NoShuffle = *noShuffle
+	app.Weighted = *weighted
+	app.MistakesOnly = *mistakes
+	app.SessionLimit = *limit
+	app.NewPerDay = *newPerDay
+	app.ReviewsPerDay = *reviewsPerDay
+	if *timed {
+		app.TimePerQuestion = time.Duration(*timePerQ) * time.Second
+	}
+	// A -scheduler given on the command line applies to all open decks and
+	// is stored in them; otherwise each deck keeps its own.
+	app.Scheduler = *scheduler
+	if stored := app.DeckScheduler(app.FilePath); !flagWasSet(flag.CommandLine, "scheduler") && slices.Contains(schedulerNames, stored) {
+		app.Scheduler = stored
+	}
+	if flagWasSet(flag.CommandLine, "scheduler") {
+		changed := false
+		for _, path := range app.Paths() {
+			if app.DeckScheduler(path) != app.Scheduler {
+				app.SetDeckScheduler(path, app.Scheduler)
+				changed = true
+			}
+		}
+		if changed && !readOnlyRun && !app.ReadOnly {
+			if err := app.saveFlashcards(); err == nil {
+				pterm.Info.Printf("'%s' now uses the %s scheduler by default.\n", app.Name(), app.Scheduler)
+			}
+		}
+	}
+
+	if *merge != "" {
+		result, err := app.mergeFile(*merge)
+		if err != nil {
+			pterm.Error.Printf("Could not merge '%s': %v\n", *merge, err)
+			exit(1)
+		}
+		pterm.Success.Printf("Merged '%s' into '%s': %d cards added (%d with a new ID), %d duplicates resolved.\n",
+			*merge, app.FilePath, result.Added, result.Renumbered, result.Deduped)
+		return
+	}
+
+	if *gen > 0 {
+		if err := app.generateCards(*gen); err != nil {
+			pterm.Error.Printf("Could not save the generated cards: %v\n", err)
+			exit(1)
+		}
+		pterm.Success.Printf("Added %d synthetic cards to '%s'.\n", *gen, app.FilePath)
+	}
+
+	if *bench {
+		if err := runBenchmark(*filePath); err != nil {
+			pterm.Error.Printf("Benchmark failed: %v\n", err)
+			exit(1)
+		}
+		return
+	}
+	if *gen > 0 {
+		return
+	}
+
+	if *deleteCategory != "" {
+		if app.deleteByCategory(*deleteCategory) == 0 {
+			exit(1)
+		}
+		return
+	}
+
+	if *deleteRange != "" {
+		if app.deleteByIDRange(deleteFrom, deleteTo) == 0 {
+			exit(1)
+		}
+		return
+	}
+
+	if *exportJSONL != "" {
+		count, err := app.exportJSONL(*exportJSONL)
+		if err != nil {
+			pterm.Error.Printf("Could not export JSON lines to '%s': %v\n", *exportJSONL, err)
+			exit(1)
+		}
+		pterm.Success.Printf("Exported %d cards to '%s'.\n", count, *exportJSONL)
+		return
+	}
+
+	if *importJSONL != "" {
+		imported, skipped, err := app.importJSONL(*importJSONL)
+		if err != nil {
+			pterm.Error.Printf("Could not import JSON lines file '%s': %v\n", *importJSONL, err)
+			exit(1)
+		}
+		pterm.Success.Printf("Imported %d cards from '%s' into '%s' (%d skipped).\n", imported, *importJSONL, app.FilePath, skipped)
+		return
+	}
+
+	if *importTxt != "" {
+		imported, skipped, err := app.importText(*importTxt, *category)
+		if err != nil {
+			pterm.Error.Printf("Could not import text file '%s': %v\n", *importTxt, err)
+			exit(1)
+		}
+		pterm.Success.Printf("Imported %d cards from '%s' into '%s' (%d skipped).\n", imported, *importTxt, app.FilePath, skipped)
+		return
+	}
+
+	if *importAnki != "" {
+		imported, skipped, err := app.importAnki(*importAnki, *category)
+		if err != nil {
+			pterm.Error.Printf("Could not import Anki file '%s': %v\n", *importAnki, err)
+			exit(1)
+		}
+		pterm.Success.Printf("Imported %d cards from '%s' into '%s' (%d skipped).\n", imported, *importAnki, app.FilePath, skipped)
+		return
+	}
+
+	if *importQuizlet != "" {
+		count, err := app.importQuizlet(*importQuizlet, unescapeSeparator(*quizletTermSep), unescapeSeparator(*quizletRowSep), *category)
+		if err != nil {
+			pterm.Error.Printf("Could not import Quizlet file '%s': %v\n", *importQuizlet, err)
+			exit(1)
+		}
+		pterm.Success.Printf("Imported %d cards from '%s' into '%s'.\n", count, *importQuizlet, app.FilePath)
+		return
+	}
+
+	if *exportCSV != "" {
+		count, err := app.exportCSV(*exportCSV)
+		if err != nil {
+			pterm.Error.Printf("Could not export CSV to '%s': %v\n", *exportCSV, err)
+			exit(1)
+		}
+		pterm.Success.Printf("Exported %d cards to '%s'.\n", count, *exportCSV)
+		return
 	}
 
-	if len(displayCards) == 0 {
-		if categoryFilter != "" {
-			pterm.Warning.Printf("No cards found in category '%s' in '%s'.\n", categoryFilter, app.FilePath)
-		} else {
-			pterm.Warning.Printf("No flashcards available in '%s'.\n", app.FilePath)
+	if *exportCategory != "" {
+		count, err := app.exportCategory(*category, *exportCategory)
+		if err != nil {
+			pterm.Error.Printf("Could not export category '%s' to '%s': %v\n", *category, *exportCategory, err)
+			exit(1)
+		}
+		if count == 0 {
+			pterm.Warning.Printf("No cards in category '%s'; nothing written.\n", *category)
+			exit(1)
 		}
+		pterm.Success.Printf("Wrote %d cards of category '%s' to '%s'.\n", count, *category, *exportCategory)
 		return
 	}
 
-	sort.SliceStable(displayCards, func(i, j int) bool {
-		return displayCards[i].ID < displayCards[j].ID
-	})
+	if *exportMD != "" {
+		count, err := app.exportMarkdown(*exportMD, *category)
+		if err != nil {
+			pterm.Error.Printf("Could not export Markdown to '%s': %v\n", *exportMD, err)
+			exit(1)
+		}
+		pterm.Success.Printf("Wrote %d cards to '%s'.\n", count, *exportMD)
+		return
+	}
 
-	tableData := pterm.TableData{
-		{"ID", "Category", "Question", "Answer(s)", "Type", "Reviewed", "Correct %"},
+	if *importCSV != "" {
+		count, err := app.importCSV(*importCSV)
+		if err != nil {
+			pterm.Error.Printf("Could not import CSV file '%s': %v\n", *importCSV, err)
+			exit(1)
+		}
+		pterm.Success.Printf("Imported %d cards from '%s' into '%s'.\n", count, *importCSV, app.FilePath)
+		return
 	}
 
-	for _, card := range displayCards {
-		cardType := "Text"
-		if len(card.Options) > 0 {
-			cardType = "Multiple Choice"
+	if *exportProgress != "" {
+		count, err := app.exportProgress(*exportProgress)
+		if err != nil {
+			pterm.Error.Printf("Could not export progress to '%s': %v\n", *exportProgress, err)
+			exit(1)
 		}
+		pterm.Success.Printf("Exported progress of %d cards to '%s'.\n", count, *exportProgress)
+		return
+	}
 
-		answerText := card.Answer
-		if len(card.CorrectAnswers) > 1 {
-			answerText = fmt.Sprintf("%s (+%d more)", card.CorrectAnswers[0], len(card.CorrectAnswers)-1)
-		} else if len(card.CorrectAnswers) == 1 {
-			answerText = card.CorrectAnswers[0]
+	if *importProgress != "" {
+		count, err := app.importProgress(*importProgress)
+		if err != nil {
+			pterm.Error.Printf("Could not import progress from '%s': %v\n", *importProgress, err)
+			exit(1)
 		}
+		pterm.Success.Printf("Merged progress of %d cards into '%s'.\n", count, app.FilePath)
+		return
+	}
 
-		qShort := card.Question
-		if len(qShort) > 40 {
-			qShort = qShort[:37] + "..."
+	if *search != "" {
+		matches, err := app.Search(*search)
+		if err != nil {
+			pterm.Error.Printf("Invalid search: %v\n", err)
+			exit(1)
 		}
-		aShort := answerText
-		if len(aShort) > 30 {
-			aShort = aShort[:27] + "..."
+		if len(matches) == 0 {
+			pterm.Warning.Printf("No cards match '%s'.\n", *search)
+			exit(1)
 		}
-		catShort := card.Category
-		if len(catShort) > 15 {
-			catShort = catShort[:12] + "..."
+		if *format == "table" {
+			app.renderHighlightedTable(matches, deck.QuestionTerms(*search))
+			return
 		}
-
-		reviewedCount := fmt.Sprintf("%d", card.TimesReviewed)
-		correctPercent := "N/A"
-		if card.TimesReviewed > 0 {
-			percent := (float64(card.TimesCorrect) / float64(card.TimesReviewed)) * 100
-			correctPercent = fmt.Sprintf("%.0f%%", percent)
+		if err := app.printCards(matches, *format); err != nil {
+			pterm.Error.Printf("Could not print search results: %v\n", err)
+			exit(1)
 		}
-
-		tableData = append(tableData, []string{
-			strconv.Itoa(card.ID),
-			catShort,
-			qShort,
-			aShort,
-			cardType,
-			reviewedCount,
-			correctPercent,
-		})
+		return
 	}
 
-	pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
-}
+	if *list {
+		cards := deck.FilterByCategory(app.Flashcards, *category)
+		if app.TagFilter != "" {
+			cards = deck.FilterByTag(cards, app.TagFilter)
+		}
+		if *flaggedOnly {
+			cards = deck.OnlyFlagged(cards)
+		}
+		cards, hidden := app.withoutMastered(cards)
+		if hidden > 0 {
+			pterm.Info.Printf("+%d mastered hidden (use -all to show them).\n", hidden)
+		}
+		if err := app.printCards(cards, *format); err != nil {
+			pterm.Error.Printf("Could not list flashcards: %v\n", err)
+			exit(1)
+		}
+		return
+	}
 
-func (app *FlashcardApp) getCategories() []string {
-	categoryMap := make(map[string]bool)
-	for _, card := range app.Flashcards {
-		categoryMap[card.Category] = true
+	if *unreviewed {
+		if *format == "table" {
+			app.listUnreviewed(*category)
+			return
+		}
+		cards := deck.FilterByCategory(app.Flashcards, *category)
+		if app.TagFilter != "" {
+			cards = deck.FilterByTag(cards, app.TagFilter)
+		}
+		if err := app.printCards(onlyUnreviewed(cards), *format); err != nil {
+			pterm.Error.Printf("Could not list flashcards: %v\n", err)
+			exit(1)
+		}
+		return
 	}
 
-	categories := make([]string, 0, len(categoryMap))
-	for category := range categoryMap {
-		categories = append(categories, category)
+	if *stats {
+		app.showStats()
+		return
 	}
-	sort.Strings(categories)
-	return categories
-}
 
-func (app *FlashcardApp) deleteCard(cardID int) bool {
-	indexToDelete := -1
-	var deletedQuestion string
-	for i, card := range app.Flashcards {
-		if card.ID == cardID {
-			indexToDelete = i
-			deletedQuestion = card.Question
-			break
+	if *show != 0 {
+		if !app.showCard(*show) {
+			exit(1)
 		}
+		return
 	}
 
-	if indexToDelete != -1 {
-		app.Flashcards = append(app.Flashcards[:indexToDelete], app.Flashcards[indexToDelete+1:]...)
-		err := app.saveFlashcards()
-		if err == nil {
-			pterm.Success.Printf("Deleted card (ID: %d) from '%s': %s\n", cardID, app.FilePath, deletedQuestion)
-			return true
+	if *validate {
+		issues := app.validateDeck()
+		if len(issues) == 0 {
+			pterm.Success.Printf("All %d cards in '%s' look fine.\n", len(app.Flashcards), app.FilePath)
+			return
 		}
-	} else {
-		pterm.Error.Printf("Card with ID %d not found in '%s'.\n", cardID, app.FilePath)
+		tableData := pterm.TableData{{"ID", "Problem"}}
+		fixable := 0
+		for _, issue := range issues {
+			tableData = append(tableData, []string{strconv.Itoa(issue.CardID), issue.Problem})
+			if len(issue.Missing) > 0 {
+				fixable++
+			}
+		}
+		pterm.Warning.Printf("Found %d problems in '%s':\n", len(issues), app.FilePath)
+		pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+		if fixable > 0 && isTerminal(os.Stdin) && isTerminal(os.Stdout) {
+			fix, _ := pterm.DefaultInteractiveConfirm.
+				WithDefaultValue(false).
+				WithConfirmText("y").WithRejectText("n").
+				Show(fmt.Sprintf("Add the missing correct answers to the options of %d cards?", fixable))
+			if fix {
+				fixed := app.fixMissingOptions(issues)
+				if err := app.saveFlashcards(); err != nil {
+					exit(1)
+				}
+				pterm.Success.Printf("Fixed %d cards.\n", fixed)
+				if fixed == len(issues) {
+					return
+				}
+			}
+		}
+		exit(1)
 	}
-	return false
-}
 
-func (app *FlashcardApp) selectCategory(prompt string, allowAll bool) string {
-	categories := app.getCategories()
-	if len(categories) == 0 && !allowAll {
-		pterm.Warning.Println("No categories available yet.")
-		return ""
+	if *forgetCategory {
+		app.forgetCategory()
+		pterm.Success.Printf("Forgot the remembered category of '%s'.\n", app.FilePath)
+		return
 	}
 
-	options := []string{}
-	if allowAll {
-		options = append(options, "[All Categories]")
+	if *resetStats {
+		count, err := app.resetStats(*category)
+		if err != nil {
+			pterm.Error.Printf("Could not reset stats: %v\n", err)
+			exit(1)
+		}
+		pterm.Success.Printf("Reset the stats of %d cards in '%s'.\n", count, app.FilePath)
+		return
 	}
-	options = append(options, categories...)
 
-	if len(options) == 0 {
-		pterm.Warning.Println("No categories defined yet.")
-		return ""
+	if *profileName != "" {
+		profiles, err := app.loadProfiles()
+		if err != nil {
+			pterm.Error.Printf("Could not read study profiles from '%s': %v\n", app.profilesPath(), err)
+			exit(1)
+		}
+		profile, ok := findProfile(profiles, *profileName)
+		if !ok {
+			pterm.Error.Printf("No study profile named '%s' in '%s'.\n", *profileName, app.profilesPath())
+			exit(1)
+		}
+		app.runProfile(profile)
+		return
 	}
 
-	selected, _ := pterm.DefaultInteractiveSelect.
-		WithOptions(options).
-		WithDefaultText(prompt).
-		Show()
-
-	if allowAll && selected == "[All Categories]" {
-		return ""
+	if !*quiet {
+		app.startupSummary()
 	}
-	if selected == "" {
-		pterm.Warning.Println("No category selected.")
-		return ""
+	if !*noWeakAlert {
+		app.weakCategoryAlert(*weakThreshold)
 	}
-	return selected
-}
-
-func main() {
-	filePath := flag.String("file", "flashcards.json", "Path to the flashcards JSON file")
-
-	flag.Parse()
-
-	rand.Seed(time.Now().UnixNano())
-
-	app := NewFlashcardApp(*filePath)
 
 	for {
-		pterm.DefaultHeader.Printf("=== GO FLASHCARD APP ('%s') ===", app.FilePath)
-		options := []string{
-			"1. Add new flashcard",
-			"2. Review flashcards",
-			"3. Quiz mode",
-			"4. List flashcards",
-			"5. Delete a flashcard",
-			"6. Exit",
+		header := fmt.Sprintf("=== GO FLASHCARD APP ('%s') ===  %d cards due today", app.Name(), app.dueCount())
+		if streak := streakText(app.currentStreak()); streak != "" {
+			header += "  " + streak
 		}
+		pterm.DefaultHeader.Println(header)
 		selectedOption, _ := pterm.DefaultInteractiveSelect.
-			WithOptions(options).
-			WithMaxHeight(10).
+			WithOptions(menuOptions()).
+			WithMaxHeight(len(mainMenu)).
 			WithDefaultText("Select an action").
 			Show()
 
@@ -551,68 +6233,72 @@ func main() {
 			continue
 		}
 
-		choice := strings.Split(selectedOption, ".")[0]
+		choice := ""
+		if n, err := strconv.Atoi(strings.Split(selectedOption, ".")[0]); err == nil && n >= 1 && n <= len(mainMenu) {
+			choice = mainMenu[n-1].Action
+		}
 
 		switch choice {
-		case "1":
+		case "add":
 			question, _ := pterm.DefaultInteractiveTextInput.Show("Enter question")
-			answer, _ := pterm.DefaultInteractiveTextInput.Show("Enter the 'main' answer (used if not multiple choice)")
-			category, _ := pterm.DefaultInteractiveTextInput.Show("Enter category (leave blank for 'General')")
-
-			isMultipleChoice, _ := pterm.DefaultInteractiveConfirm.
-				WithConfirmText("y").WithRejectText("n").
-				Show("Make this a multiple choice question?")
+			app.promptNewCard(question)
 
-			var mcOptions []string
-			var mcCorrectAnswers []string
+		case "add-multiple":
+			app.addMultipleCards()
 
-			if isMultipleChoice {
-				pterm.Info.Println("Enter options (type 'done' when finished, need at least 2):")
-				optionCount := 1
-				for {
-					optionText, _ := pterm.DefaultInteractiveTextInput.
-						Show(fmt.Sprintf("Option %d", optionCount))
-
-					trimmedOption := strings.ToLower(strings.TrimSpace(optionText))
-					if trimmedOption == "done" {
-						if len(mcOptions) < 2 {
-							pterm.Warning.Println("Need at least 2 options for multiple choice. Please add more.")
-							continue
-						}
-						break
-					}
+		case "quick-add":
+			app.quickAdd()
 
-					if optionText != "" {
-						mcOptions = append(mcOptions, optionText)
-						isCorrect, _ := pterm.DefaultInteractiveConfirm.
-							WithConfirmText("y").WithRejectText("n").
-							Show(fmt.Sprintf("Is '%s' a correct answer?", optionText))
-						if isCorrect {
-							mcCorrectAnswers = append(mcCorrectAnswers, optionText)
-						}
-						optionCount++
-					} else if trimmedOption != "done" {
-						pterm.Warning.Println("Option cannot be empty. Please enter text or type 'done'.")
-					}
-				}
+		case "review":
+			if len(app.Flashcards) == 0 {
+				pterm.Warning.Println("No cards to review yet. Add some first!")
+				continue
 			}
+			category := app.selectCategory("Select category to review", true)
+			app.rememberCategory(category)
+			difficulty := selectDifficultyFilter("Select difficulty to review")
+			app.reviewCards(category, difficulty)
 
-			app.addCard(question, answer, category, mcOptions, mcCorrectAnswers)
+		case "study-due":
+			if app.dueCount() == 0 {
+				pterm.Success.Println("Nothing is due today. Well done!")
+				continue
+			}
+			app.overdueFirst = true
+			app.reviewCards("", "")
+			app.overdueFirst = false
 
-		case "2":
+		case "review-boxes":
 			if len(app.Flashcards) == 0 {
 				pterm.Warning.Println("No cards to review yet. Add some first!")
 				continue
 			}
-			category := app.selectCategory("Select category to review", true)
-			app.reviewCards(category)
+			app.reviewDueBoxes()
 
-		case "3":
+		case "mistakes":
+			prevMistakesOnly := app.MistakesOnly
+			app.MistakesOnly = true
+			app.reviewCards("", "")
+			app.MistakesOnly = prevMistakesOnly
+
+		case "quiz":
 			if len(app.Flashcards) == 0 {
 				pterm.Warning.Println("No cards for a quiz yet. Add some first!")
 				continue
 			}
-			category := app.selectCategory("Select category for quiz", true)
+			category := app.selectCategory("Select category for quiz", true, autoWeakest)
+			if category == autoWeakest {
+				weakest, ok := app.weakestCategory()
+				if ok {
+					pterm.Info.Printf("Your weakest category is '%s'.\n", weakest)
+				} else {
+					pterm.Info.Printf("No category has %d reviews yet to find the weakest one; quizzing all categories.\n", minWeakestReviews)
+				}
+				category = weakest
+			} else {
+				app.rememberCategory(category)
+			}
+			difficulty := selectDifficultyFilter("Select difficulty for quiz")
 
 			numStr, _ := pterm.DefaultInteractiveTextInput.
 				WithDefaultValue("5").
@@ -623,9 +6309,9 @@ func main() {
 				pterm.Warning.Println("Invalid number of questions, defaulting to 5.")
 				num = 5
 			}
-			app.quizMode(category, num)
+			app.quizMode(category, difficulty, num)
 
-		case "4":
+		case "list":
 			if len(app.Flashcards) == 0 {
 				pterm.Warning.Println("No cards to list yet.")
 				continue
@@ -633,13 +6319,158 @@ func main() {
 			category := app.selectCategory("Select category to list", true)
 			app.listCards(category)
 
-		case "5":
+		case "tags":
+			app.browseTags()
+
+		case "unreviewed":
+			if len(app.Flashcards) == 0 {
+				pterm.Warning.Println("No cards to list yet.")
+				continue
+			}
+			app.listUnreviewed(app.selectCategory("Select category to check", true))
+
+		case "show":
+			if len(app.Flashcards) == 0 {
+				pterm.Warning.Println("No cards to show yet.")
+				continue
+			}
+			idStr, _ := pterm.DefaultInteractiveTextInput.Show("Enter ID of card to view")
+			id, err := strconv.Atoi(strings.TrimSpace(idStr))
+			if err != nil {
+				pterm.Error.Println("Invalid ID entered.")
+			} else {
+				app.showCard(id)
+			}
+
+		case "stats":
+			app.showStats()
+
+		case "cram":
+			if len(app.Flashcards) == 0 {
+				pterm.Warning.Println("No cards to cram yet. Add some first!")
+				continue
+			}
+			category := app.selectCategory("Select category to cram", true)
+			app.rememberCategory(category)
+			app.cramMode(category)
+
+		case "undo":
+			app.undoLastSession()
+
+		case "leaderboard":
+			app.showLeaderboard()
+
+		case "trend":
+			app.showTrend()
+
+		case "duplicates":
+			groups := app.findDuplicates()
+			if len(groups) == 0 {
+				pterm.Success.Println("No duplicate questions found.")
+				continue
+			}
+			pterm.Warning.Printf("%d questions appear more than once:\n", len(groups))
+			for _, group := range groups {
+				pterm.DefaultSection.WithLevel(2).Println(group[0].Question)
+				app.renderCardTable(group)
+			}
+			pterm.Info.Println("Edit or delete the extra cards to clean them up.")
+
+		case "profiles":
+			if len(app.Flashcards) == 0 {
+				pterm.Warning.Println("No cards to study yet. Add some first!")
+				continue
+			}
+			app.manageProfiles()
+
+		case "edit":
+			if len(app.Flashcards) == 0 {
+				pterm.Warning.Println("No cards to edit.")
+				continue
+			}
+			app.listCardsToPick()
+
+			idStr, _ := pterm.DefaultInteractiveTextInput.
+				Show("Enter ID of card to edit")
+			id, err := strconv.Atoi(strings.TrimSpace(idStr))
+			if err != nil {
+				pterm.Error.Println("Invalid ID entered.")
+			} else {
+				app.editCard(id)
+			}
+
+		case "edit-correct":
+			choiceCards := []Flashcard{}
+			for _, card := range app.Flashcards {
+				if len(card.Options) > 0 {
+					choiceCards = append(choiceCards, card)
+				}
+			}
+			if len(choiceCards) == 0 {
+				pterm.Warning.Println("No multiple-choice cards to edit.")
+				continue
+			}
+			app.renderCardTable(choiceCards)
+
+			idStr, _ := pterm.DefaultInteractiveTextInput.
+				Show("Enter ID of card to edit")
+			id, err := strconv.Atoi(strings.TrimSpace(idStr))
+			if err != nil {
+				pterm.Error.Println("Invalid ID entered.")
+			} else {
+				app.editCorrectAnswers(id)
+			}
+
+		case "rename-category":
+			oldName := app.selectCategory("Select category to rename", false)
+			if oldName == "" {
+				continue
+			}
+			newName, _ := pterm.DefaultInteractiveTextInput.Show(fmt.Sprintf("New name for '%s' (an existing category merges)", oldName))
+			count, err := app.renameCategory(oldName, newName)
+			if err != nil {
+				pterm.Error.Printf("Category not renamed: %v.\n", err)
+			} else {
+				pterm.Success.Printf("Updated %d cards from '%s' to '%s'.\n", count, oldName, strings.TrimSpace(newName))
+			}
+
+		case "replace":
+			find, _ := pterm.DefaultInteractiveTextInput.Show("Text to find (case-sensitive)")
+			if find == "" {
+				continue
+			}
+			replace, _ := pterm.DefaultInteractiveTextInput.Show(fmt.Sprintf("Replace '%s' with", find))
+			const inBoth, inQuestions, inAnswers = "Questions and answers", "Questions only", "Answers only"
+			fields, _ := pterm.DefaultInteractiveSelect.
+				WithOptions([]string{inBoth, inQuestions, inAnswers}).
+				WithDefaultText("Where to replace").
+				Show()
+			replacements, cards, err := app.findReplace(find, replace, fields != inAnswers, fields != inQuestions)
+			if err != nil {
+				pterm.Error.Printf("Nothing replaced: %v.\n", err)
+			} else if cards > 0 {
+				pterm.Success.Printf("Made %d replacements in %d cards.\n", replacements, cards)
+			}
+
+		case "search":
+			query, _ := pterm.DefaultInteractiveTextInput.
+				Show("Search (e.g. verb, category:French question:être, accuracy:<50)")
+			matches, err := app.Search(query)
+			if err != nil {
+				pterm.Error.Printf("Invalid search: %v\n", err)
+			} else if len(matches) == 0 {
+				pterm.Warning.Printf("No cards match '%s'.\n", query)
+			} else {
+				pterm.Info.Printf("%d matching cards:\n", len(matches))
+				app.renderHighlightedTable(matches, deck.QuestionTerms(query))
+			}
+
+		case "delete":
 			if len(app.Flashcards) == 0 {
 				pterm.Warning.Println("No cards to delete.")
 				continue
 			}
-			pterm.Info.Println("Current cards:")
-			app.listCards("")
+			app.listCardsToPick()
 
 			idStr, _ := pterm.DefaultInteractiveTextInput.
 				Show("Enter ID of card to delete")
@@ -650,7 +6481,90 @@ func main() {
 				app.deleteCard(id)
 			}
 
-		case "6":
+		case "bulk-delete":
+			if len(app.Flashcards) == 0 {
+				pterm.Warning.Println("No cards to delete.")
+				continue
+			}
+			const byCategory, byRange = "By category", "By ID range"
+			how, _ := pterm.DefaultInteractiveSelect.
+				WithOptions([]string{byCategory, byRange}).
+				WithDefaultText("Delete cards").
+				Show()
+			if how == byCategory {
+				if category := app.selectCategory("Select category to delete", false); category != "" {
+					app.deleteByCategory(category)
+				}
+				continue
+			}
+			rangeStr, _ := pterm.DefaultInteractiveTextInput.Show("ID range to delete (e.g. 10-25)")
+			from, to, err := parseIDRange(rangeStr)
+			if err != nil {
+				pterm.Error.Println(err)
+				continue
+			}
+			app.deleteByIDRange(from, to)
+
+		case "trash":
+			app.manageTrash()
+
+		case "move":
+			if len(app.Flashcards) == 0 {
+				pterm.Warning.Println("No cards to move.")
+				continue
+			}
+			app.listCardsToPick()
+
+			idStr, _ := pterm.DefaultInteractiveTextInput.
+				Show("Enter ID of card to move")
+			id, err := strconv.Atoi(strings.TrimSpace(idStr))
+			if err != nil {
+				pterm.Error.Println("Invalid ID entered.")
+				continue
+			}
+			destPath := app.selectDeck("Select destination deck")
+			if destPath == "" {
+				pterm.Warning.Println("No destination selected.")
+				continue
+			}
+			if err := app.moveCard(id, destPath); err != nil {
+				pterm.Error.Printf("Could not move card: %v\n", err)
+			}
+
+		case "flagged":
+			app.reviewFlaggedCards()
+
+		case "reset":
+			if len(app.Flashcards) == 0 {
+				pterm.Warning.Println("No cards to reset yet.")
+				continue
+			}
+			category := app.selectCategory("Select category to reset", true)
+			count := len(deck.FilterByCategory(app.Flashcards, category))
+			confirm, _ := pterm.DefaultInteractiveConfirm.
+				WithDefaultValue(false).
+				WithConfirmText("y").WithRejectText("n").
+				Show(fmt.Sprintf("Reset the stats of %d cards? This can't be undone", count))
+			if !confirm {
+				pterm.Info.Println("Stats kept.")
+				continue
+			}
+			if reset, err := app.resetStats(category); err == nil {
+				pterm.Success.Printf("Reset the stats of %d cards.\n", reset)
+			}
+
+		case "reverse":
+			app.Reverse = !app.Reverse
+			if app.Reverse {
+				pterm.Success.Println("Reverse mode on: review and quiz show the answer and ask for the question.")
+			} else {
+				pterm.Success.Println("Reverse mode off: back to question-to-answer.")
+			}
+
+		case "help":
+			showHelp()
+
+		case "exit":
 			pterm.Info.Println("Goodbye!")
 			return
 
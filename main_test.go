@@ -0,0 +1,808 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"flashcards-go/deck"
+	"github.com/pterm/pterm"
+)
+
+func TestNormalizeAnswer(t *testing.T) {
+	tests := []struct {
+		answer, want string
+	}{
+		{"Paris", "paris"},
+		{"Paris.", "paris"},
+		{"  Paris!  ", "paris"},
+		{"être", "etre"},
+		{"Crème Brûlée.", "creme brulee"},
+		{"New   York", "new york"},
+		{"¿Qué?", "que"},
+		{"rock'n'roll", "rock'n'roll"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := normalizeAnswer(tt.answer); got != tt.want {
+			t.Errorf("normalizeAnswer(%q) = %q, want %q", tt.answer, got, tt.want)
+		}
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"", "abc", 3},
+		{"paris", "paris", 0},
+		{"paris", "pariss", 1},
+		{"paris", "pairs", 2},
+		{"kitten", "sitting", 3},
+		{"été", "ete", 2},
+	}
+	for _, tt := range tests {
+		if got := levenshtein(tt.a, tt.b); got != tt.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestFuzzyMatch(t *testing.T) {
+	const threshold = 0.15
+	tests := []struct {
+		given, expected string
+		want            bool
+	}{
+		{"Paris", "Paris", true},
+		{"paris.", "Paris", true},
+		{"etre", "être", true},
+		{"Mississipi", "Mississippi", true},
+		{"photosyntesis", "photosynthesis", true},
+		{"cat", "car", false},
+		{"London", "Paris", false},
+		{"photograph", "photosynthesis", false},
+		{"", "Paris", false},
+		{"...", "Paris", false},
+	}
+	for _, tt := range tests {
+		if got := fuzzyMatch(tt.given, tt.expected, threshold); got != tt.want {
+			t.Errorf("fuzzyMatch(%q, %q) = %v, want %v", tt.given, tt.expected, got, tt.want)
+		}
+	}
+}
+
+func TestValidateMCOptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  []string
+		correct  []string
+		errorHas string // "" for valid options
+	}{
+		{"valid", []string{"Paris", "London", "Rome"}, []string{"Paris"}, ""},
+		{"several correct", []string{"2", "3", "4", "5"}, []string{"2", "3", "5"}, ""},
+		{"correct ignores case and spaces", []string{"Paris", "London"}, []string{" paris "}, ""},
+		{"too few options", []string{"Paris"}, []string{"Paris"}, "at least 2 options"},
+		{"no options", nil, nil, "at least 2 options"},
+		{"empty option", []string{"Paris", "  "}, []string{"Paris"}, "cannot be empty"},
+		{"duplicate option", []string{"Paris", "London", " paris"}, []string{"Paris"}, "listed twice"},
+		{"no correct option", []string{"Paris", "London"}, nil, "at least one correct"},
+		{"correct not an option", []string{"Paris", "London"}, []string{"Rome"}, "not one of the options"},
+		{"no incorrect option", []string{"Paris", "London"}, []string{"Paris", "London"}, "at least one incorrect"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateMCOptions(tt.options, tt.correct)
+			switch {
+			case tt.errorHas == "" && err != nil:
+				t.Errorf("validateMCOptions(%q, %q) = %v, want no error", tt.options, tt.correct, err)
+			case tt.errorHas != "" && err == nil:
+				t.Errorf("validateMCOptions(%q, %q) succeeded, want an error containing %q", tt.options, tt.correct, tt.errorHas)
+			case tt.errorHas != "" && !strings.Contains(err.Error(), tt.errorHas):
+				t.Errorf("validateMCOptions(%q, %q) = %v, want an error containing %q", tt.options, tt.correct, err, tt.errorHas)
+			}
+		})
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	cfg, err := loadConfig()
+	if err != nil || cfg.File != "" || cfg.Fuzzy != nil || cfg.DelayMs != nil {
+		t.Errorf("loadConfig() without a file = %+v, %v; want an empty config", cfg, err)
+	}
+
+	rc := filepath.Join(home, ".flashcardsrc")
+	if err := os.WriteFile(rc, []byte(`{"file":"~/decks/main.json","fuzzy":true,"delay_ms":250}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err = loadConfig()
+	if err != nil {
+		t.Fatalf("loadConfig(): %v", err)
+	}
+	if want := filepath.Join(home, "decks", "main.json"); cfg.File != want {
+		t.Errorf("File = %q, want %q", cfg.File, want)
+	}
+	if cfg.Fuzzy == nil || !*cfg.Fuzzy {
+		t.Errorf("Fuzzy = %v, want true", cfg.Fuzzy)
+	}
+	if cfg.DelayMs == nil || *cfg.DelayMs != 250 {
+		t.Errorf("DelayMs = %v, want 250", cfg.DelayMs)
+	}
+
+	if err := os.WriteFile(rc, []byte(`{"file":`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadConfig(); err == nil {
+		t.Error("loadConfig() of a broken file succeeded, want an error")
+	}
+}
+
+func TestDeckPath(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   []string
+		env    string
+		config string
+		want   string
+	}{
+		{"default", nil, "", "", "flashcards.json"},
+		{"config", nil, "", "config.json", "config.json"},
+		{"env over config", nil, "env.json", "config.json", "env.json"},
+		{"flag over env and config", []string{"-file", "flag.json"}, "env.json", "config.json", "flag.json"},
+		{"flag set to the default", []string{"-file", "flashcards.json"}, "env.json", "config.json", "flashcards.json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("FLASHCARDS_FILE", tt.env)
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			filePath := fs.String("file", "flashcards.json", "")
+			if err := fs.Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+			if got := deckPath(fs, *filePath, config{File: tt.config}); got != tt.want {
+				t.Errorf("deckPath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSameAnswer(t *testing.T) {
+	tests := []struct {
+		given, expected string
+		caseSensitive   bool
+		want            bool
+	}{
+		{"Paris", "Paris", false, true},
+		{"paris", "Paris", false, true},
+		{"PARIS", "paris", false, true},
+		{"Paris", "London", false, false},
+		{"Paris", "Paris", true, true},
+		{"paris", "Paris", true, false},
+		{"H2O", "h2o", true, false},
+		{"Paris", "London", true, false},
+	}
+	for _, tt := range tests {
+		app := &FlashcardApp{CaseSensitive: tt.caseSensitive}
+		if got := app.sameAnswer(tt.given, tt.expected); got != tt.want {
+			t.Errorf("sameAnswer(%q, %q) with CaseSensitive=%v = %v, want %v", tt.given, tt.expected, tt.caseSensitive, got, tt.want)
+		}
+	}
+}
+
+// seedRNG makes the package's random source deterministic for one test.
+func seedRNG(t *testing.T, seed int64) {
+	t.Helper()
+	saved := rng
+	rng = rand.New(rand.NewSource(seed))
+	t.Cleanup(func() { rng = saved })
+}
+
+func TestSelectWeightedDistribution(t *testing.T) {
+	seedRNG(t, 1)
+	cards := []Flashcard{
+		{ID: 1, TimesReviewed: 10, TimesCorrect: 0},  // always missed
+		{ID: 2, TimesReviewed: 10, TimesCorrect: 10}, // always right
+		{ID: 3}, // never reviewed
+		{ID: 4, TimesReviewed: 10, TimesCorrect: 5},
+	}
+	drawn := map[int]int{}
+	for range 10000 {
+		picked := selectWeighted(cards, 1)
+		if len(picked) != 1 {
+			t.Fatalf("selectWeighted(cards, 1) returned %d cards", len(picked))
+		}
+		drawn[picked[0].ID]++
+	}
+	t.Logf("draws by card ID: %v", drawn)
+	if drawn[1] <= drawn[4] || drawn[4] <= drawn[2] {
+		t.Errorf("draws %v: want always missed > half right > always right", drawn)
+	}
+	if drawn[3] <= drawn[4] {
+		t.Errorf("draws %v: want never reviewed drawn more than half right", drawn)
+	}
+	if drawn[2] == 0 {
+		t.Errorf("draws %v: the always-right card was never drawn", drawn)
+	}
+}
+
+func TestSelectWeightedDistinct(t *testing.T) {
+	seedRNG(t, 2)
+	cards := []Flashcard{{ID: 1}, {ID: 2}, {ID: 3}, {ID: 4}, {ID: 5}}
+	for _, n := range []int{0, 3, 5, 8} {
+		picked := selectWeighted(cards, n)
+		if want := min(n, len(cards)); len(picked) != want {
+			t.Errorf("selectWeighted(cards, %d) returned %d cards, want %d", n, len(picked), want)
+		}
+		seen := map[int]bool{}
+		for _, card := range picked {
+			if seen[card.ID] {
+				t.Errorf("selectWeighted(cards, %d) drew card %d twice", n, card.ID)
+			}
+			seen[card.ID] = true
+		}
+	}
+	if len(cards) != 5 || cards[0].ID != 1 || cards[4].ID != 5 {
+		t.Errorf("selectWeighted changed its input: %v", cards)
+	}
+}
+
+func TestGenerateDistractors(t *testing.T) {
+	seedRNG(t, 3)
+	app := &FlashcardApp{Deck: &deck.Deck{Flashcards: []Flashcard{
+		{ID: 1, Question: "Capital of France?", Answer: "Paris", Category: "Geography"},
+		{ID: 2, Question: "Capital of Spain?", Answer: "Madrid", Category: "geography"},
+		{ID: 3, Question: "Capital of Italy?", Answer: "Rome", CorrectAnswers: []string{"Rome", "Roma"}, Category: "Geography"},
+		{ID: 4, Question: "Largest city of Spain?", Answer: " madrid ", Category: "Geography"},
+		{ID: 5, Question: "Describe Paris.", Answer: "Lyon", Category: "Geography", Essay: true},
+		{ID: 6, Question: "2+2?", Answer: "4", Category: "Math"},
+		{ID: 7, Question: "Untitled", Answer: "General answer", Category: "General"},
+	}}}
+
+	tests := []struct {
+		name              string
+		correct, category string
+		n                 int
+		want              []string // the candidates, in any order
+	}{
+		{"enough candidates", "Paris", "Geography", 2, []string{"Madrid", "Rome", "Roma"}},
+		{"too few returns all", "Paris", "Geography", 10, []string{"Madrid", "Rome", "Roma"}},
+		{"correct answer left out ignoring case", "rome", "Geography", 10, []string{"Paris", "Madrid", "Roma"}},
+		{"only one candidate", "5", "Math", 3, []string{"4"}},
+		{"no candidates", "4", "Math", 3, nil},
+		{"unknown category", "x", "Music", 3, nil},
+		{"empty category is General", "x", "", 3, []string{"General answer"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := app.generateDistractors(tt.correct, tt.category, tt.n)
+			if want := min(tt.n, len(tt.want)); len(got) != want {
+				t.Fatalf("generateDistractors(%q, %q, %d) = %q, want %d options", tt.correct, tt.category, tt.n, got, want)
+			}
+			seen := map[string]bool{}
+			for _, option := range got {
+				if !deck.ContainsFold(tt.want, option) {
+					t.Errorf("generateDistractors(%q, %q, %d) returned %q, not one of %q", tt.correct, tt.category, tt.n, option, tt.want)
+				}
+				if seen[strings.ToLower(option)] {
+					t.Errorf("generateDistractors(%q, %q, %d) returned %q twice", tt.correct, tt.category, tt.n, option)
+				}
+				seen[strings.ToLower(option)] = true
+			}
+		})
+	}
+}
+
+func TestParseNumber(t *testing.T) {
+	tests := []struct {
+		s    string
+		want float64
+		ok   bool
+	}{
+		{"3", 3, true},
+		{"3.0", 3, true},
+		{" -0.5 ", -0.5, true},
+		{".5", 0.5, true},
+		{"+7", 7, true},
+		{"1,000", 1000, true},
+		{"1,234,567.5", 1234567.5, true},
+		{"1e3", 1000, true},
+		{"3,5", 0, false},
+		{"1,00", 0, false},
+		{"0x10", 0, false},
+		{"inf", 0, false},
+		{"NaN", 0, false},
+		{"three", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseNumber(tt.s)
+		if ok != tt.ok || (ok && got != tt.want) {
+			t.Errorf("parseNumber(%q) = %v, %v; want %v, %v", tt.s, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestSameAnswerNumeric(t *testing.T) {
+	tests := []struct {
+		given, expected string
+		want            bool
+	}{
+		{"1,000", "1000", true},
+		{"1000", "1,000", true},
+		{"3.0", "3", true},
+		{"0.50", ".5", true},
+		{"3", "4", false},
+		{"3.01", "3", false},
+		{"Three", "three", true},
+		{"three", "3", false},
+		{"3,5", "3.5", false},
+	}
+	for _, tt := range tests {
+		app := &FlashcardApp{NumericAnswers: true}
+		if got := app.sameAnswer(tt.given, tt.expected); got != tt.want {
+			t.Errorf("sameAnswer(%q, %q) with NumericAnswers = %v, want %v", tt.given, tt.expected, got, tt.want)
+		}
+	}
+
+	app := &FlashcardApp{}
+	if app.sameAnswer("3.0", "3") {
+		t.Error(`sameAnswer("3.0", "3") without NumericAnswers = true, want false`)
+	}
+	app.CaseSensitive, app.NumericAnswers = true, true
+	if app.sameAnswer("Three", "three") {
+		t.Error(`sameAnswer("Three", "three") with CaseSensitive and NumericAnswers = true, want false`)
+	}
+}
+
+func TestChosenOption(t *testing.T) {
+	options := []string{"St. Louis", "Washington, D.C.", "1. Lyon", "Paris"}
+	labels := append(numberedOptions(options), flagChoice)
+	if want := "1. St. Louis"; labels[0] != want {
+		t.Errorf("first label = %q, want %q", labels[0], want)
+	}
+	for i, option := range options {
+		if got := chosenOption(labels, options, labels[i]); got != option {
+			t.Errorf("chosenOption(%q) = %q, want %q", labels[i], got, option)
+		}
+	}
+	for _, selected := range []string{flagChoice, "", "5. Rome", "Paris"} {
+		if got := chosenOption(labels, options, selected); got != "" {
+			t.Errorf("chosenOption(%q) = %q, want no option", selected, got)
+		}
+	}
+}
+
+// quiet silences pterm for the rest of a test or benchmark.
+func quiet(tb testing.TB) {
+	pterm.DisableOutput()
+	tb.Cleanup(pterm.EnableOutput)
+}
+
+// generatedDeck writes a deck of n synthetic cards made by generateCards to
+// a temporary directory and returns its path.
+func generatedDeck(tb testing.TB, n int) string {
+	tb.Helper()
+	quiet(tb)
+	path := filepath.Join(tb.TempDir(), "flashcards.json")
+	if err := newDeckApp(path).generateCards(n); err != nil {
+		tb.Fatalf("generateCards(%d): %v", n, err)
+	}
+	return path
+}
+
+// loadedApp loads the deck at path, failing the test if it can't.
+func loadedApp(tb testing.TB, path string) *FlashcardApp {
+	tb.Helper()
+	app := newDeckApp(path)
+	if err := app.loadFlashcards(); err != nil {
+		tb.Fatalf("loading %s: %v", path, err)
+	}
+	return app
+}
+
+func TestGenerateCards(t *testing.T) {
+	seedRNG(t, 4)
+	app := loadedApp(t, generatedDeck(t, 20))
+	if len(app.Flashcards) != 20 {
+		t.Fatalf("generated deck has %d cards, want 20", len(app.Flashcards))
+	}
+	seen := map[int]bool{}
+	for i, card := range app.Flashcards {
+		if seen[card.ID] {
+			t.Errorf("card ID %d used twice", card.ID)
+		}
+		seen[card.ID] = true
+		if !strings.HasPrefix(card.Question, fmt.Sprintf("Synthetic %d:", card.ID)) {
+			t.Errorf("card %d has question %q", card.ID, card.Question)
+		}
+		if wantOptions := i%4 == 3; (len(card.Options) == 4) != wantOptions {
+			t.Errorf("card %d has options %q, want multiple choice: %v", card.ID, card.Options, wantOptions)
+		}
+		if len(card.CorrectAnswers) != 1 || card.CorrectAnswers[0] != card.Answer {
+			t.Errorf("card %d has correct answers %q, want [%q]", card.ID, card.CorrectAnswers, card.Answer)
+		}
+	}
+
+	// The same seed generates the same deck.
+	seedRNG(t, 4)
+	again := loadedApp(t, generatedDeck(t, 20))
+	for i, card := range again.Flashcards {
+		if card.Question != app.Flashcards[i].Question || card.Answer != app.Flashcards[i].Answer {
+			t.Errorf("card %d differs with the same seed: %q / %q", card.ID, card.Question, app.Flashcards[i].Question)
+		}
+	}
+}
+
+func TestSimulateQuiz(t *testing.T) {
+	seedRNG(t, 5)
+	app := loadedApp(t, generatedDeck(t, 9))
+	if got := app.simulateQuiz(100); got != 6 {
+		t.Errorf("simulateQuiz(100) on 9 cards = %d correct, want 6", got)
+	}
+	reviewed, correct := 0, 0
+	for _, card := range app.Flashcards {
+		reviewed += card.TimesReviewed
+		correct += card.TimesCorrect
+		if card.TimesReviewed != 1 || card.LastReviewed == nil || card.LastCorrect == nil {
+			t.Errorf("card %d not updated once: %+v", card.ID, card)
+		}
+	}
+	if reviewed != 9 || correct != 6 {
+		t.Errorf("stats after the quiz: %d reviewed, %d correct; want 9 and 6", reviewed, correct)
+	}
+}
+
+func TestRunBenchmark(t *testing.T) {
+	seedRNG(t, 6)
+	path := generatedDeck(t, 50)
+	before, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := runBenchmark(path); err != nil {
+		t.Fatalf("runBenchmark: %v", err)
+	}
+	if after, err := os.ReadFile(path); err != nil || string(after) != string(before) {
+		t.Errorf("runBenchmark changed the deck file (%v)", err)
+	}
+
+	if err := runBenchmark(filepath.Join(t.TempDir(), "empty.json")); err == nil {
+		t.Error("runBenchmark on an empty deck succeeded, want an error")
+	}
+}
+
+func BenchmarkLoad(b *testing.B) {
+	path := generatedDeck(b, 5000)
+	b.ResetTimer()
+	for range b.N {
+		loadedApp(b, path)
+	}
+}
+
+func BenchmarkSave(b *testing.B) {
+	app := loadedApp(b, generatedDeck(b, 5000))
+	b.ResetTimer()
+	for range b.N {
+		if err := app.saveFlashcards(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSelectWeighted(b *testing.B) {
+	app := loadedApp(b, generatedDeck(b, 5000))
+	app.simulateQuiz(len(app.Flashcards))
+	b.ResetTimer()
+	for range b.N {
+		selectWeighted(app.Flashcards, benchQuestions)
+	}
+}
+
+func BenchmarkSimulateQuiz(b *testing.B) {
+	app := loadedApp(b, generatedDeck(b, 5000))
+	b.ResetTimer()
+	for range b.N {
+		app.simulateQuiz(benchQuestions)
+	}
+}
+
+// importApp returns an app in the given import mode holding one reviewed
+// multiple-choice card.
+func importApp(mode string) *FlashcardApp {
+	return &FlashcardApp{ImportMode: mode, Deck: &deck.Deck{Flashcards: []Flashcard{{
+		ID: 7, Question: "Capital of France?", Answer: "Paris",
+		CorrectAnswers: []string{"Paris"}, Options: []string{"Paris", "Lyon"},
+		Category: "Geography", TimesReviewed: 5, TimesCorrect: 4, Box: 3,
+	}}}}
+}
+
+func TestImportGuard(t *testing.T) {
+	duplicate := Flashcard{
+		ID: 8, Question: "  capital of   FRANCE? ", Answer: "paris",
+		CorrectAnswers: []string{"paris", "Paris, France"}, Options: []string{"paris", "Marseille", "Nice"},
+		PrimaryAnswer: "Paris, France", Category: "Imported",
+	}
+	fresh := Flashcard{ID: 9, Question: "Capital of Spain?", Answer: "Madrid", CorrectAnswers: []string{"Madrid"}}
+
+	t.Run(importAppend, func(t *testing.T) {
+		app := importApp(importAppend)
+		guard := app.newImportGuard()
+		if !guard.add(duplicate) || !guard.add(fresh) || !guard.add(fresh) {
+			t.Error("append mode left out a card")
+		}
+		if len(app.Flashcards) != 4 || guard.Duplicates != 0 || guard.Replaced != 0 || guard.changed() {
+			t.Errorf("%d cards, %d duplicates, %d replaced; want 4, 0, 0", len(app.Flashcards), guard.Duplicates, guard.Replaced)
+		}
+	})
+
+	t.Run(importSkipDupes, func(t *testing.T) {
+		app := importApp(importSkipDupes)
+		guard := app.newImportGuard()
+		if guard.add(duplicate) {
+			t.Error("the duplicate of an existing card was added")
+		}
+		if !guard.add(fresh) {
+			t.Error("a new card was left out")
+		}
+		if guard.add(fresh) {
+			t.Error("a card repeated within the import was added twice")
+		}
+		if len(app.Flashcards) != 2 || guard.Duplicates != 2 || guard.Replaced != 0 || guard.changed() {
+			t.Errorf("%d cards, %d duplicates, %d replaced; want 2, 2, 0", len(app.Flashcards), guard.Duplicates, guard.Replaced)
+		}
+		if app.Flashcards[0].Answer != "Paris" {
+			t.Errorf("the existing card changed: %+v", app.Flashcards[0])
+		}
+	})
+
+	t.Run(importReplace, func(t *testing.T) {
+		app := importApp(importReplace)
+		guard := app.newImportGuard()
+		if guard.add(duplicate) {
+			t.Error("the duplicate of an existing card was added")
+		}
+		if len(app.Flashcards) != 1 || guard.Replaced != 1 || guard.Duplicates != 0 || !guard.changed() {
+			t.Errorf("%d cards, %d duplicates, %d replaced; want 1, 0, 1", len(app.Flashcards), guard.Duplicates, guard.Replaced)
+		}
+		card := app.Flashcards[0]
+		if card.ID != 7 || card.TimesReviewed != 5 || card.TimesCorrect != 4 || card.Box != 3 {
+			t.Errorf("replace lost the ID or stats: %+v", card)
+		}
+		if card.Question != "Capital of France?" || card.Category != "Geography" {
+			t.Errorf("replace changed the question or category: %q, %q", card.Question, card.Category)
+		}
+		if card.Answer != duplicate.Answer || card.PrimaryAnswer != duplicate.PrimaryAnswer ||
+			!slices.Equal(card.CorrectAnswers, duplicate.CorrectAnswers) || !slices.Equal(card.Options, duplicate.Options) {
+			t.Errorf("replace did not update the answers and options: %+v", card)
+		}
+
+		// A question repeated within the import replaces the card added
+		// earlier in it.
+		if !guard.add(fresh) {
+			t.Error("a new card was left out")
+		}
+		update := fresh
+		update.ID, update.Answer, update.CorrectAnswers = 10, "Madrid, Spain", []string{"Madrid, Spain"}
+		if guard.add(update) {
+			t.Error("a card repeated within the import was added twice")
+		}
+		if len(app.Flashcards) != 2 || app.Flashcards[1].ID != 9 || app.Flashcards[1].Answer != "Madrid, Spain" {
+			t.Errorf("cards after the repeated question: %+v", app.Flashcards)
+		}
+	})
+}
+
+func TestReplaceAnswer(t *testing.T) {
+	tests := []struct {
+		answers              []string
+		oldAnswer, newAnswer string
+		want                 []string
+	}{
+		{[]string{"Paris"}, "Paris", "Paris, France", []string{"Paris, France"}},
+		{[]string{"colour", "color", "hue"}, "colour", "shade", []string{"shade", "color", "hue"}},
+		{[]string{"color", "colour"}, "Colour", "tint", []string{"color", "tint"}},
+		{[]string{"color", "colour"}, "colour", "color", []string{"color"}},
+		{[]string{"hue"}, "colour", "shade", []string{"shade", "hue"}},
+		{nil, "", "Paris", []string{"Paris"}},
+	}
+	for _, tt := range tests {
+		if got := replaceAnswer(tt.answers, tt.oldAnswer, tt.newAnswer); !slices.Equal(got, tt.want) {
+			t.Errorf("replaceAnswer(%q, %q, %q) = %q, want %q", tt.answers, tt.oldAnswer, tt.newAnswer, got, tt.want)
+		}
+	}
+}
+
+func TestUnusedPath(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "deck-missed.json")
+	if got := unusedPath(path); got != path {
+		t.Errorf("unusedPath of a new file = %q, want %q", got, path)
+	}
+	for _, name := range []string{"deck-missed.json", "deck-missed-2.json"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := unusedPath(path), filepath.Join(dir, "deck-missed-3.json"); got != want {
+		t.Errorf("unusedPath = %q, want %q", got, want)
+	}
+}
+
+func TestExportCategoryKeepsExistingFile(t *testing.T) {
+	quiet(t)
+	dest := filepath.Join(t.TempDir(), "spanish.json")
+	if err := os.WriteFile(dest, []byte("keep"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	app := &FlashcardApp{Deck: &deck.Deck{FilePath: filepath.Join(t.TempDir(), "flashcards.json"), Flashcards: []Flashcard{
+		{ID: 4, Question: "hola", Answer: "hello", CorrectAnswers: []string{"hello"}, Category: "Spanish"},
+	}}}
+
+	if _, err := app.exportCategory("spanish", dest); err == nil {
+		t.Error("exportCategory replaced an existing file without Force")
+	}
+	if data, _ := os.ReadFile(dest); string(data) != "keep" {
+		t.Errorf("the existing file now holds %q", data)
+	}
+
+	app.Force = true
+	if count, err := app.exportCategory("spanish", dest); err != nil || count != 1 {
+		t.Fatalf("exportCategory with Force = %d, %v; want 1 card written", count, err)
+	}
+	written := loadedApp(t, dest)
+	if len(written.Flashcards) != 1 || written.Flashcards[0].ID != 1 {
+		t.Errorf("exported deck holds %+v, want the card renumbered to 1", written.Flashcards)
+	}
+}
+
+// moveApps writes a source deck with two cards and an empty destination
+// deck, and returns the loaded source app and the destination path.
+func moveApps(t *testing.T) (*FlashcardApp, string) {
+	t.Helper()
+	quiet(t)
+	dir := t.TempDir()
+	source := filepath.Join(dir, "source.json")
+	dest := filepath.Join(dir, "dest.json")
+	cards := `{"version":3,"cards":[{"id":1,"question":"a","answer":"1","correct_answers":["1"]},{"id":2,"question":"b","answer":"2","correct_answers":["2"]}]}`
+	if err := os.WriteFile(source, []byte(cards), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dest, []byte(`{"version":3,"cards":[{"id":1,"question":"x","answer":"y","correct_answers":["y"]}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return loadedApp(t, source), dest
+}
+
+func TestMoveCard(t *testing.T) {
+	app, dest := moveApps(t)
+	if err := app.moveCard(1, dest); err != nil {
+		t.Fatalf("moveCard: %v", err)
+	}
+	if _, err := os.Stat(deck.LockPath(dest)); !os.IsNotExist(err) {
+		t.Errorf("the destination is still locked after the move: %v", err)
+	}
+	source := loadedApp(t, app.FilePath)
+	if len(source.Flashcards) != 1 || source.Flashcards[0].ID != 2 {
+		t.Errorf("source deck holds %+v, want only card 2", source.Flashcards)
+	}
+	moved := loadedApp(t, dest)
+	if len(moved.Flashcards) != 2 || moved.Flashcards[1].Question != "a" || moved.Flashcards[1].ID != 2 {
+		t.Errorf("destination deck holds %+v, want card a added as ID 2", moved.Flashcards)
+	}
+}
+
+func TestMoveCardRollsBack(t *testing.T) {
+	app, dest := moveApps(t)
+	app.ReadOnly = true // the source can't be saved
+	if err := app.moveCard(1, dest); err == nil {
+		t.Fatal("moveCard succeeded although the source could not be saved")
+	}
+	if len(app.Flashcards) != 2 || app.Flashcards[0].ID != 1 || app.Flashcards[1].ID != 2 {
+		t.Errorf("source cards in memory are %+v, want cards 1 and 2 in order", app.Flashcards)
+	}
+	if index, found := app.CardIndex(1); !found || index != 0 {
+		t.Errorf("CardIndex(1) = %d, %v; want 0, true", index, found)
+	}
+	moved := loadedApp(t, dest)
+	if len(moved.Flashcards) != 1 || moved.Flashcards[0].Question != "x" {
+		t.Errorf("destination deck holds %+v after the rollback, want only its own card", moved.Flashcards)
+	}
+}
+
+func TestRecordQuizResultKeepsBrokenScores(t *testing.T) {
+	quiet(t)
+	app := newDeckApp(filepath.Join(t.TempDir(), "flashcards.json"))
+	app.recordQuizResult("", 80, 10)
+	scores, err := app.loadScores()
+	if err != nil || len(scores) != 1 || scores[0].Category != "All" {
+		t.Fatalf("loadScores() = %+v, %v; want the one quiz", scores, err)
+	}
+
+	broken := []byte(`[{"deck":"flashcards.json",`)
+	if err := os.WriteFile(app.scoresPath(), broken, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := app.loadScores(); err == nil {
+		t.Error("loadScores() of a broken file succeeded, want an error")
+	}
+	app.recordQuizResult("", 90, 10)
+	if data, _ := os.ReadFile(app.scoresPath()); string(data) != string(broken) {
+		t.Errorf("recordQuizResult overwrote the broken scores file with %q", data)
+	}
+}
+
+func TestRecordSessionKeepsBrokenHistory(t *testing.T) {
+	quiet(t)
+	app := newDeckApp(filepath.Join(t.TempDir(), "flashcards.json"))
+	app.recordSession("quiz", "", 10, 7)
+	records, err := app.loadHistory()
+	if err != nil || len(records) != 1 || records[0].Correct != 7 {
+		t.Fatalf("loadHistory() = %+v, %v; want the one session", records, err)
+	}
+
+	broken := []byte(`[{"deck":`)
+	if err := os.WriteFile(app.historyPath(), broken, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := app.loadHistory(); err == nil {
+		t.Error("loadHistory() of a broken file succeeded, want an error")
+	}
+	app.recordSession("quiz", "", 10, 8)
+	if data, _ := os.ReadFile(app.historyPath()); string(data) != string(broken) {
+		t.Errorf("recordSession overwrote the broken history file with %q", data)
+	}
+}
+
+func TestAverageAnswerTime(t *testing.T) {
+	if _, ok := averageAnswerTime(Flashcard{TotalTimeMs: 5000}); ok {
+		t.Error("a card without timed answers has an average")
+	}
+	if got, ok := averageAnswerTime(Flashcard{TotalTimeMs: 9000, TimesAnswered: 4}); !ok || got != 2250*time.Millisecond {
+		t.Errorf("averageAnswerTime = %v, %v; want 2.25s", got, ok)
+	}
+}
+
+func TestIsMastered(t *testing.T) {
+	next := time.Now().Add(48 * time.Hour)
+	tests := []struct {
+		name string
+		card Flashcard
+		want bool
+	}{
+		{"new card", Flashcard{}, false},
+		{"unscheduled, accurate", Flashcard{TimesReviewed: 10, TimesCorrect: 9}, true},
+		{"unscheduled, too few reviews", Flashcard{TimesReviewed: 4, TimesCorrect: 4}, false},
+		{"unscheduled, inaccurate", Flashcard{TimesReviewed: 10, TimesCorrect: 8}, false},
+		{"scheduled, short interval", Flashcard{TimesReviewed: 10, TimesCorrect: 10, Interval: 3, NextReview: &next}, false},
+		{"scheduled, long interval", Flashcard{TimesReviewed: 2, TimesCorrect: 2, Interval: masteredInterval, NextReview: &next}, true},
+		{"last Leitner box", Flashcard{Box: len(leitnerIntervals), NextReview: &next}, true},
+	}
+	for _, tt := range tests {
+		if got := isMastered(tt.card); got != tt.want {
+			t.Errorf("%s: isMastered = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSimulateQuizWithoutCorrectAnswers(t *testing.T) {
+	app := &FlashcardApp{NoShuffle: true, Deck: &deck.Deck{Flashcards: []Flashcard{
+		{ID: 1, Question: "a", Answer: "1"},
+		{ID: 2, Question: "b", Answer: "2", CorrectAnswers: []string{}},
+		{ID: 3, Question: "c", Answer: "3"},
+	}}}
+	if got := app.simulateQuiz(3); got != 2 {
+		t.Errorf("simulateQuiz(3) = %d correct, want 2", got)
+	}
+}